@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"myapi/config"
+	"myapi/pkg/db"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func NewMigrateCommand() *cobra.Command {
+	var configFilePath string
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "执行数据库表结构的版本化迁移",
+		Args:  cobra.NoArgs,
+		// 参数校验通过后的错误不再打印用法
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&configFilePath, "config", "c", "./etc/config.yaml", "配置文件路径")
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "执行所有未执行的迁移",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := connectDB(configFilePath); err != nil {
+				return err
+			}
+			done, err := db.MigrateUp(context.Background())
+			for _, m := range done {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "已执行 %06d_%s\n", m.Version, m.Name)
+			}
+			if err == nil && len(done) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "已是最新版本")
+			}
+			return err
+		},
+	}
+
+	down := &cobra.Command{
+		Use:   "down [steps]",
+		Short: "回滚最近执行的迁移，默认回滚1个",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps := 1
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return errors.Errorf("回滚数量必须是正整数: %s", args[0])
+				}
+				steps = n
+			}
+			if _, err := connectDB(configFilePath); err != nil {
+				return err
+			}
+			done, err := db.MigrateDown(context.Background(), steps)
+			for _, m := range done {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "已回滚 %06d_%s\n", m.Version, m.Name)
+			}
+			if err == nil && len(done) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "没有可以回滚的迁移")
+			}
+			return err
+		},
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "查看迁移的执行状态",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := connectDB(configFilePath); err != nil {
+				return err
+			}
+			statuses, err := db.GetMigrationStatus(context.Background())
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED_AT")
+			for _, s := range statuses {
+				appliedAt := "pending"
+				if s.AppliedAt != nil {
+					appliedAt = s.AppliedAt.Format(time.DateTime)
+				}
+				_, _ = fmt.Fprintf(w, "%06d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+// connectDB 读取配置文件并连接数据库，供不启动服务的子命令使用
+func connectDB(configFilePath string) (*config.GlobalConfig, error) {
+	cfg, err := config.TryLoadFromDisk(configFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取本地配置文件错误")
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		return nil, errors.Errorf("本地配置文件验证错误:%s", strings.Join(msgs, "; "))
+	}
+	if err := db.InitTiDB(cfg); err != nil {
+		return nil, errors.Wrap(err, "数据库连接错误")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,572 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"myapi/config"
+	"myapi/pkg/audit"
+	"myapi/pkg/models"
+	"myapi/pkg/server"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// modelsOptions models子命令的公共参数
+type modelsOptions struct {
+	configFilePath string
+	server         string
+	output         string
+	actor          string
+}
+
+// modelAPI 调用模型管理接口，指定服务地址时通过HTTP访问，否则在进程内直接处理请求
+type modelAPI struct {
+	baseURL string
+	client  *http.Client
+	actor   string
+	close   func()
+}
+
+// apiResponse 接口响应，Data延迟解析
+type apiResponse struct {
+	Status int             `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Msg    string          `json:"msg"`
+}
+
+// handlerTransport 将请求交给进程内的处理器，不经过网络
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RemoteAddr = "127.0.0.1:0"
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+func NewModelsCommand() *cobra.Command {
+	opts := &modelsOptions{}
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "管理模型注册表",
+		Args:  cobra.NoArgs,
+		// 参数校验通过后的错误不再打印用法
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&opts.configFilePath, "config", "c", "./etc/config.yaml", "配置文件路径，未指定--server时直接连接配置中的数据库")
+	flags.StringVarP(&opts.server, "server", "s", "", "服务地址，如 http://localhost:3000，指定后通过HTTP接口操作")
+	flags.StringVarP(&opts.output, "output", "o", "table", "输出格式，可选值为 table|json|yaml")
+	flags.StringVar(&opts.actor, "actor", os.Getenv("USER"), "写入修订记录和审计日志的操作人")
+	cmd.AddCommand(
+		newModelsListCommand(opts),
+		newModelsGetCommand(opts),
+		newModelsCreateCommand(opts),
+		newModelsUpdateCommand(opts),
+		newModelsDeleteCommand(opts),
+		newModelsTestCommand(opts),
+		newModelsEnableVisionCommand(opts),
+	)
+	return cmd
+}
+
+func newModelsListCommand(opts *modelsOptions) *cobra.Command {
+	var query models.ModelListQuery
+	var enabled string
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "查询模型列表",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := url.Values{}
+			setParam(params, "type", query.Type)
+			setParam(params, "name", query.Name)
+			setParam(params, "enabled", enabled)
+			setParam(params, "status", query.Status)
+			setParam(params, "sort", query.Sort)
+			setParam(params, "order", query.Order)
+			if query.IncludeDeleted {
+				params.Set("include_deleted", "true")
+			}
+			params.Set("page_size", "100")
+
+			return withModelAPI(opts, func(api *modelAPI) error {
+				// 按游标取完所有页，limit大于0时最多返回limit个
+				list := make([]models.Model, 0)
+				for {
+					var page struct {
+						List       []models.Model `json:"list"`
+						NextCursor string         `json:"next_cursor"`
+					}
+					if _, err := api.do(http.MethodGet, "/api/v1/models/get?"+params.Encode(), nil, nil, &page); err != nil {
+						return err
+					}
+					list = append(list, page.List...)
+					if page.NextCursor == "" || (limit > 0 && len(list) >= limit) {
+						break
+					}
+					params.Set("cursor", page.NextCursor)
+				}
+				if limit > 0 && len(list) > limit {
+					list = list[:limit]
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, list, true)
+			})
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&query.Type, "type", "", "按模型类型过滤")
+	flags.StringVar(&query.Name, "name", "", "按名称子串过滤")
+	flags.StringVar(&enabled, "enabled", "", "按启用状态过滤，true|false")
+	flags.StringVar(&query.Status, "status", "", "按健康状态过滤，healthy|degraded|down|unknown")
+	flags.StringVar(&query.Sort, "sort", "", "排序字段，name|created_at|updated_at")
+	flags.StringVar(&query.Order, "order", "", "排序方向，asc|desc")
+	flags.BoolVar(&query.IncludeDeleted, "include-deleted", false, "包括已删除的模型")
+	flags.IntVar(&limit, "limit", 0, "最多返回的模型数，0表示全部")
+	return cmd
+}
+
+func newModelsGetCommand(opts *modelsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <model_id>",
+		Short: "查询单个模型",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withModelAPI(opts, func(api *modelAPI) error {
+				var model models.Model
+				if _, err := api.do(http.MethodGet, "/api/v1/models/"+url.PathEscape(args[0]), nil, nil, &model); err != nil {
+					return err
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, model, false)
+			})
+		},
+	}
+}
+
+// modelFieldFlags 创建和更新模型共用的字段参数
+type modelFieldFlags struct {
+	file       string
+	name       string
+	endpoint   string
+	apiKey     string
+	apiKeyEnv  string
+	timeout    int
+	modelType  string
+	dimensions int
+}
+
+func (f *modelFieldFlags) register(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&f.file, "file", "f", "", "从YAML或JSON文件读取模型字段，- 表示标准输入；命令行参数优先")
+	flags.StringVar(&f.name, "name", "", "模型名称")
+	flags.StringVar(&f.endpoint, "endpoint", "", "接口地址")
+	flags.StringVar(&f.apiKey, "api-key", "", "API Key")
+	flags.StringVar(&f.apiKeyEnv, "api-key-env", "", "从环境变量读取API Key，避免密钥出现在命令历史中")
+	flags.IntVar(&f.timeout, "timeout", 0, "超时时间（秒）")
+	flags.StringVar(&f.modelType, "type", "", "模型类型")
+	flags.IntVar(&f.dimensions, "dimensions", 0, "向量维度，embedding 类型必填")
+}
+
+// body 合并文件和命令行参数，只包含指定了的字段
+func (f *modelFieldFlags) body(cmd *cobra.Command) (map[string]any, error) {
+	body := make(map[string]any)
+	if f.file != "" {
+		var data []byte
+		var err error
+		if f.file == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(f.file)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "读取模型文件失败")
+		}
+		if err := yaml.Unmarshal(data, &body); err != nil {
+			return nil, errors.Wrap(err, "解析模型文件失败")
+		}
+	}
+	flags := cmd.Flags()
+	if flags.Changed("name") {
+		body["name"] = f.name
+	}
+	if flags.Changed("endpoint") {
+		body["endpoint"] = f.endpoint
+	}
+	if flags.Changed("api-key") {
+		body["api_key"] = f.apiKey
+	}
+	if flags.Changed("api-key-env") {
+		key, ok := os.LookupEnv(f.apiKeyEnv)
+		if !ok || key == "" {
+			return nil, errors.Errorf("环境变量%s未设置", f.apiKeyEnv)
+		}
+		body["api_key"] = key
+	}
+	if flags.Changed("timeout") {
+		body["timeout"] = f.timeout
+	}
+	if flags.Changed("type") {
+		body["type"] = f.modelType
+	}
+	if flags.Changed("dimensions") {
+		body["dimensions"] = f.dimensions
+	}
+	return body, nil
+}
+
+func newModelsCreateCommand(opts *modelsOptions) *cobra.Command {
+	var fields modelFieldFlags
+	var verify bool
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "创建模型",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := fields.body(cmd)
+			if err != nil {
+				return err
+			}
+			path := "/api/v1/models/create"
+			if verify {
+				path += "?verify=true"
+			}
+			return withModelAPI(opts, func(api *modelAPI) error {
+				var model models.Model
+				if _, err := api.do(http.MethodPost, path, nil, body, &model); err != nil {
+					return err
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, model, false)
+			})
+		},
+	}
+	fields.register(cmd)
+	cmd.Flags().BoolVar(&verify, "verify", false, "保存前测试连通性，未通过则不创建")
+	return cmd
+}
+
+func newModelsUpdateCommand(opts *modelsOptions) *cobra.Command {
+	var fields modelFieldFlags
+	var ifMatch int
+	cmd := &cobra.Command{
+		Use:   "update <model_id>",
+		Short: "更新模型，只修改指定的字段",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := fields.body(cmd)
+			if err != nil {
+				return err
+			}
+			if len(body) == 0 {
+				return errors.New("没有需要更新的字段")
+			}
+			return withModelAPI(opts, func(api *modelAPI) error {
+				var model models.Model
+				if _, err := api.do(http.MethodPut, "/api/v1/models/"+url.PathEscape(args[0]), ifMatchHeader(ifMatch), body, &model); err != nil {
+					return err
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, model, false)
+			})
+		},
+	}
+	fields.register(cmd)
+	cmd.Flags().IntVar(&ifMatch, "if-match", 0, "只在模型版本号等于该值时更新")
+	return cmd
+}
+
+func newModelsDeleteCommand(opts *modelsOptions) *cobra.Command {
+	var ifMatch int
+	var purge bool
+	cmd := &cobra.Command{
+		Use:   "delete <model_id>",
+		Short: "删除模型，默认为软删除",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "/api/v1/models/" + url.PathEscape(args[0])
+			if purge {
+				path += "/purge"
+			}
+			return withModelAPI(opts, func(api *modelAPI) error {
+				var model models.Model
+				msg, err := api.do(http.MethodDelete, path, ifMatchHeader(ifMatch), nil, &model)
+				if err != nil {
+					return err
+				}
+				if opts.output == "table" {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg, model.Name)
+					return err
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, model, false)
+			})
+		},
+	}
+	cmd.Flags().IntVar(&ifMatch, "if-match", 0, "只在模型版本号等于该值时删除")
+	cmd.Flags().BoolVar(&purge, "purge", false, "彻底删除已软删除的模型，无法恢复")
+	return cmd
+}
+
+func newModelsTestCommand(opts *modelsOptions) *cobra.Command {
+	var upstream string
+	cmd := &cobra.Command{
+		Use:   "test <model_id>",
+		Short: "测试模型连通性",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var body any
+			if upstream != "" {
+				body = models.ModelTestRequest{Model: upstream}
+			}
+			return withModelAPI(opts, func(api *modelAPI) error {
+				var result models.ModelTestResult
+				if _, err := api.do(http.MethodPost, "/api/v1/models/"+url.PathEscape(args[0])+"/test", nil, body, &result); err != nil {
+					return err
+				}
+				if err := printTestResult(cmd.OutOrStdout(), opts.output, &result); err != nil {
+					return err
+				}
+				if !result.Success {
+					return errors.New("连通性测试未通过")
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&upstream, "model", "", "测试时使用的上游模型名，默认为模型名称")
+	return cmd
+}
+
+func newModelsEnableVisionCommand(opts *modelsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable-vision [model_id]",
+		Short: "为图片预处理使用的模型开启supports_vision，默认为配置中的images.visionModelId",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var modelID string
+			if len(args) == 1 {
+				modelID = args[0]
+			} else {
+				cfg, err := config.TryLoadFromDisk(opts.configFilePath)
+				if err != nil {
+					return errors.Wrap(err, "读取本地配置文件错误")
+				}
+				if !cfg.Images.Enabled() {
+					return errors.New("配置中没有images.visionModelId，请指定模型ID")
+				}
+				modelID = cfg.Images.VisionModelID
+			}
+			return withModelAPI(opts, func(api *modelAPI) error {
+				path := "/api/v1/models/" + url.PathEscape(modelID)
+				var model models.Model
+				if _, err := api.do(http.MethodGet, path, nil, nil, &model); err != nil {
+					return err
+				}
+				if !model.SupportsVision {
+					if !model.IsType(models.ModelTypeChat) {
+						return errors.Errorf("模型%s的类型为%s，只有chat类型的模型可以开启supports_vision", model.Name, model.Type)
+					}
+					body := map[string]any{"supports_vision": true}
+					if _, err := api.do(http.MethodPut, path, ifMatchHeader(model.Version), body, &model); err != nil {
+						return err
+					}
+				}
+				return printModels(cmd.OutOrStdout(), opts.output, model, false)
+			})
+		},
+	}
+}
+
+// withModelAPI 按参数连接服务或数据库后执行fn
+func withModelAPI(opts *modelsOptions, fn func(api *modelAPI) error) error {
+	switch opts.output {
+	case "table", "json", "yaml":
+	default:
+		return errors.Errorf("不支持的输出格式:%s，可选值为 table|json|yaml", opts.output)
+	}
+	api, err := newModelAPI(opts)
+	if err != nil {
+		return err
+	}
+	defer api.close()
+	return fn(api)
+}
+
+func newModelAPI(opts *modelsOptions) (*modelAPI, error) {
+	if opts.server != "" {
+		return &modelAPI{
+			baseURL: strings.TrimRight(opts.server, "/"),
+			client:  &http.Client{Timeout: 2 * time.Minute},
+			actor:   opts.actor,
+			close:   func() {},
+		}, nil
+	}
+
+	cfg, err := connectDB(opts.configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	auditor, err := audit.NewLogger(cfg.Audit)
+	if err != nil {
+		return nil, err
+	}
+	return &modelAPI{
+		baseURL: "http://localhost",
+		client:  &http.Client{Transport: handlerTransport{handler: server.NewLocalHandler(auditor)}},
+		actor:   opts.actor,
+		close: func() {
+			_ = auditor.Close()
+		},
+	}, nil
+}
+
+// do 发送请求并将响应的data解析到out，返回响应的msg；接口返回错误时以msg作为错误信息
+func (a *modelAPI) do(method, path string, header http.Header, body any, out any) (string, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.baseURL+path, reader)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.actor != "" {
+		req.Header.Set("X-Actor", a.actor)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "请求服务失败")
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errors.Errorf("解析响应失败: HTTP %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", errors.Errorf("%s (HTTP %d)", result.Msg, resp.StatusCode)
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return "", errors.Wrap(err, "解析响应数据失败")
+		}
+	}
+	return result.Msg, nil
+}
+
+// printModels 输出模型，table格式不显示API Key
+func printModels(out io.Writer, output string, v any, isList bool) error {
+	if output != "table" {
+		return printStructured(out, output, v)
+	}
+	var list []models.Model
+	if isList {
+		list = v.([]models.Model)
+	} else {
+		list = []models.Model{v.(models.Model)}
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "MODEL_ID\tNAME\tTYPE\tSTATE\tHEALTH\tVERSION\tENDPOINT")
+	for _, m := range list {
+		state := "enabled"
+		if m.DeletedAt.Valid {
+			state = "deleted"
+		} else if !m.Enabled {
+			state = "disabled"
+		}
+		health := "-"
+		if m.Status != nil {
+			health = m.Status.Status
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", m.ModelID, m.Name, m.Type, state, health, m.Version, m.Endpoint)
+	}
+	return w.Flush()
+}
+
+// printTestResult 输出连通性测试结果
+func printTestResult(out io.Writer, output string, result *models.ModelTestResult) error {
+	if output != "table" {
+		return printStructured(out, output, result)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "SUCCESS\t%t\n", result.Success)
+	_, _ = fmt.Fprintf(w, "REACHABLE\t%t\n", result.Reachable)
+	_, _ = fmt.Fprintf(w, "AUTHORIZED\t%t\n", result.Authorized)
+	_, _ = fmt.Fprintf(w, "STATUS_CODE\t%d\n", result.StatusCode)
+	_, _ = fmt.Fprintf(w, "LATENCY_MS\t%d\n", result.LatencyMS)
+	if result.ReturnedModel != "" {
+		_, _ = fmt.Fprintf(w, "RETURNED_MODEL\t%s\n", result.ReturnedModel)
+	}
+	if result.DimensionsMatch != nil {
+		_, _ = fmt.Fprintf(w, "DIMENSIONS\t%d (expected %d)\n", result.Dimensions, result.ExpectedDimensions)
+	}
+	if result.Error != "" {
+		_, _ = fmt.Fprintf(w, "ERROR\t%s\n", result.Error)
+	}
+	return w.Flush()
+}
+
+// printStructured 以json或yaml格式输出，yaml的字段名与json保持一致
+func printStructured(out io.Writer, output string, v any) error {
+	if output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	data, err = yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func setParam(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+// ifMatchHeader 版本号大于0时生成If-Match请求头
+func ifMatchHeader(version int) http.Header {
+	if version <= 0 {
+		return nil
+	}
+	return http.Header{"If-Match": []string{`"` + strconv.Itoa(version) + `"`}}
+}
@@ -5,10 +5,15 @@ import (
 	"errors"
 
 	"myapi/config"
+	"myapi/pkg/audit"
+	"myapi/pkg/broker"
 	"myapi/pkg/db"
+	"myapi/pkg/health"
+	"myapi/pkg/ingest"
 	"myapi/pkg/server"
 	"myapi/pkg/signals"
 	"myapi/pkg/util"
+	"myapi/pkg/vectorstore"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -18,6 +23,8 @@ import (
 
 func NewRootCommand() *cobra.Command {
 	var configFilePath string
+	var dryRun bool
+	var autoMigrate bool
 	cmd := &cobra.Command{
 		Use:   "",
 		Short: "",
@@ -42,7 +49,26 @@ func NewRootCommand() *cobra.Command {
 				zap.S().Infof("数据库连接错误:%s", err.Error())
 				return
 			}
-			zap.S().Infof("数据库连接成功，地址为：%s:%d,库名为：%s", cfg.DBConfig.Host, cfg.DBConfig.Port, cfg.DBConfig.Database)
+			zap.S().Infof("数据库连接成功，驱动为：%s,地址为：%s", db.Dialect(), cfg.DBConfig.Address())
+			if autoMigrate && !dryRun {
+				if _, err := db.MigrateUp(ctx); err != nil {
+					zap.S().Errorf("数据库迁移错误:%s", err.Error())
+					return
+				}
+			}
+			if dryRun {
+				if cfg.Models == "" {
+					zap.S().Errorf("没有配置模型文件，无法预览同步计划")
+					return
+				}
+				result, err := server.SyncModelsFromFile(cfg.Models, true)
+				if err != nil {
+					zap.S().Errorf("预览模型同步计划失败:%s", err.Error())
+					return
+				}
+				server.PrintModelSyncPlan(result)
+				return
+			}
 			if err := run(cfg, ctx); err != nil {
 				zap.S().Errorf("运行时错误:%s", err.Error())
 				return
@@ -51,13 +77,77 @@ func NewRootCommand() *cobra.Command {
 		Version: util.GetVersion().Version,
 	}
 	cmd.Flags().StringVarP(&configFilePath, "config", "c", "./etc/config.yaml", "配置文件路径")
+	cmd.Flags().BoolVar(&autoMigrate, "auto-migrate", true, "启动时执行未执行的数据库迁移，多副本部署时可以关闭后单独执行 migrate up")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只输出模型文件的同步计划，不启动服务")
 	_ = cmd.MarkFlagRequired("config")
 	_ = viper.BindPFlag("config", cmd.Flags().Lookup("config"))
+	cmd.AddCommand(NewVersionCommand())
+	cmd.AddCommand(NewModelsCommand())
+	cmd.AddCommand(NewMigrateCommand())
 	return cmd
 }
 
 func run(cfg *config.GlobalConfig, ctx context.Context) error {
-	s := server.NewServer(cfg)
+	store, err := vectorstore.New(cfg.VectorStore, cfg.Milvus)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+	if cfg.VectorStore.ResolveBackend(cfg.Milvus) == config.VectorStoreMemory {
+		zap.S().Warnf("向量存储使用内存后端，服务重启后向量数据会丢失")
+	}
+
+	var chatConsumer *broker.ChatConsumer
+	var articleConsumer *broker.ArticleConsumer
+	var imagesConsumer *broker.ImagesProcConsumer
+	if cfg.NATSConfig.Enabled() {
+		nc, err := broker.Connect(cfg.NATSConfig)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = nc.Drain()
+		}()
+		if chatConsumer, err = broker.NewChatConsumer(nc, cfg.NATSConfig); err != nil {
+			return err
+		}
+		if cfg.NATSConfig.ArticleSubject != "" && cfg.Article.Enabled() {
+			chunks := ingest.NewChunkStore(store, cfg.Article.Collection)
+			if articleConsumer, err = broker.NewArticleConsumer(nc, cfg.NATSConfig, ingest.NewArticleIngester(cfg.Article, chunks)); err != nil {
+				return err
+			}
+		}
+		if cfg.NATSConfig.ImagesProcSubject != "" && cfg.Images.Enabled() {
+			// 没有配置嵌入模型时只保存图片描述
+			var chunks *ingest.ChunkStore
+			if cfg.Images.EmbeddingModelID != "" {
+				chunks = ingest.NewChunkStore(store, cfg.Images.Collection)
+			}
+			if imagesConsumer, err = broker.NewImagesProcConsumer(nc, cfg.NATSConfig, ingest.NewImageCaptioner(cfg.Images, chunks)); err != nil {
+				return err
+			}
+		}
+	}
+
+	auditor, err := audit.NewLogger(cfg.Audit)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = auditor.Close()
+	}()
+
+	if cfg.Models != "" {
+		result, err := server.SyncModelsFromFile(cfg.Models, false)
+		if err != nil {
+			return err
+		}
+		server.LogModelSyncResult(result)
+	}
+
+	s := server.NewServer(cfg, store, auditor)
 	g, c := errgroup.WithContext(ctx)
 	g.Go(func() error {
 		return s.Run()
@@ -68,6 +158,32 @@ func run(cfg *config.GlobalConfig, ctx context.Context) error {
 		s.GracefulShutdown(ctx)
 		return nil
 	})
+	if chatConsumer != nil {
+		g.Go(func() error {
+			return chatConsumer.Run(c)
+		})
+	}
+	if articleConsumer != nil {
+		g.Go(func() error {
+			return articleConsumer.Run(c)
+		})
+	}
+	if imagesConsumer != nil {
+		g.Go(func() error {
+			return imagesConsumer.Run(c)
+		})
+	}
+	if cfg.Models != "" {
+		g.Go(func() error {
+			return server.WatchModelsFile(c, cfg.Models)
+		})
+	}
+	if cfg.Health.Enabled() {
+		prober := health.NewProber(cfg.Health)
+		g.Go(func() error {
+			return prober.Run(c)
+		})
+	}
 	return g.Wait()
 
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"myapi/pkg/util"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func NewVersionCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "打印版本信息",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := util.GetVersion()
+			out := cmd.OutOrStdout()
+			switch output {
+			case "":
+				_, _ = fmt.Fprintf(out, "Version:      %s\n", v.Version)
+				_, _ = fmt.Fprintf(out, "BuildDate:    %s\n", v.BuildDate)
+				_, _ = fmt.Fprintf(out, "GitCommit:    %s\n", v.GitCommit)
+				_, _ = fmt.Fprintf(out, "GitTag:       %s\n", v.GitTag)
+				_, _ = fmt.Fprintf(out, "GitTreeState: %s\n", v.GitTreeState)
+				_, _ = fmt.Fprintf(out, "GoVersion:    %s\n", v.GoVersion)
+				_, _ = fmt.Fprintf(out, "Compiler:     %s\n", v.Compiler)
+				_, _ = fmt.Fprintf(out, "Platform:     %s\n", v.Platform)
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(v)
+			case "yaml":
+				data, err := yaml.Marshal(v)
+				if err != nil {
+					return err
+				}
+				_, err = out.Write(data)
+				return err
+			default:
+				return errors.Errorf("不支持的输出格式:%s，可选值为 json|yaml", output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "输出格式，可选值为 json|yaml")
+	return cmd
+}
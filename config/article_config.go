@@ -0,0 +1,53 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// maxChunkSize Milvus varchar 字段最大65535字节，按每个字符最多4字节计算
+const maxChunkSize = 16000
+
+// ArticleConfig 文章入库流水线的配置
+type ArticleConfig struct {
+	// 用于生成向量的嵌入模型ID，为空时不启动文章消费者
+	EmbeddingModelID string `json:"embeddingModelId,omitempty" yaml:"embeddingModelId,omitempty"`
+	// 请求嵌入接口时使用的上游模型名称，为空时使用模型的名称
+	EmbeddingModel string `json:"embeddingModel,omitempty" yaml:"embeddingModel,omitempty"`
+	Collection     string `json:"collection,omitempty" yaml:"collection,omitempty"`
+	// 分块长度和相邻分块的重叠长度（字符数）
+	ChunkSize    int `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`
+	ChunkOverlap int `json:"chunkOverlap,omitempty" yaml:"chunkOverlap,omitempty"`
+	// 每次请求嵌入接口的分块数
+	BatchSize int `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+}
+
+func (t *ArticleConfig) Validate() []error {
+	var errs = make([]error, 0)
+	if t.Collection == "" {
+		errs = append(errs, errors.Errorf("没有指定文章向量集合名称"))
+	}
+	if t.ChunkSize <= 0 || t.ChunkSize > maxChunkSize {
+		errs = append(errs, errors.Errorf("文章分块长度必须在[1-%d]之间", maxChunkSize))
+	}
+	if t.ChunkOverlap < 0 || t.ChunkOverlap >= t.ChunkSize {
+		errs = append(errs, errors.Errorf("文章分块重叠长度必须大于等于0且小于分块长度"))
+	}
+	if t.BatchSize <= 0 {
+		errs = append(errs, errors.Errorf("文章嵌入批大小必须大于0"))
+	}
+	return errs
+}
+
+func NewDefaultArticleConfig() *ArticleConfig {
+	return &ArticleConfig{
+		Collection:   "article_chunks",
+		ChunkSize:    500,
+		ChunkOverlap: 50,
+		BatchSize:    16,
+	}
+}
+
+// Enabled 是否配置了文章入库使用的嵌入模型
+func (t *ArticleConfig) Enabled() bool {
+	return t != nil && t.EmbeddingModelID != ""
+}
@@ -0,0 +1,15 @@
+package config
+
+// AuditConfig 审计日志的配置，审计事件总是写入t_audit_log表
+type AuditConfig struct {
+	// 同时追加写入的JSON Lines文件路径，为空时只写数据库
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+func (t *AuditConfig) Validate() []error {
+	return make([]error, 0)
+}
+
+func NewDefaultAuditConfig() *AuditConfig {
+	return &AuditConfig{}
+}
@@ -6,22 +6,44 @@ import (
     "github.com/pkg/errors"
 )
 
+// 支持的数据库驱动，tidb与mysql使用相同的驱动
+const (
+    DBDriverMySQL    = "mysql"
+    DBDriverTiDB     = "tidb"
+    DBDriverPostgres = "postgres"
+    DBDriverSQLite   = "sqlite"
+)
+
 type DBConfig struct {
+    // 数据库驱动，为空时为mysql
+    Driver         string `json:"driver,omitempty" yaml:"driver,omitempty"`
     Host           string `json:"host" yaml:"host"`
     Port           int    `json:"port" yaml:"port"`
     Username       string `json:"username" yaml:"username"`
     Password       string `json:"password" yaml:"password"`
     Database       string `json:"database" yaml:"database"`
     MaxConnections int    `json:"maxConnections,omitempty" yaml:"maxConnections,omitempty"`
+    // postgres的sslmode，为空时为disable
+    SSLMode        string `json:"sslMode,omitempty" yaml:"sslMode,omitempty"`
 }
 
 func (t *DBConfig) Validate() []error {
     var errs = make([]error, 0)
-    if t.Username == "" || t.Password == "" {
-        errs = append(errs, errors.Errorf("连接的数据库用户名或密码为空"))
-    }
-    if t.Database == "" {
-        errs = append(errs, errors.Errorf("没有指定需要连接的数据库名称"))
+    switch t.Driver {
+    case "", DBDriverMySQL, DBDriverTiDB, DBDriverPostgres:
+        if t.Username == "" || t.Password == "" {
+            errs = append(errs, errors.Errorf("连接的数据库用户名或密码为空"))
+        }
+        if t.Database == "" {
+            errs = append(errs, errors.Errorf("没有指定需要连接的数据库名称"))
+        }
+    case DBDriverSQLite:
+        // sqlite的database为数据库文件路径，:memory:为内存数据库
+        if t.Database == "" {
+            errs = append(errs, errors.Errorf("没有指定sqlite数据库文件路径"))
+        }
+    default:
+        errs = append(errs, errors.Errorf("不支持的数据库驱动%s，可选值: mysql、tidb、postgres、sqlite", t.Driver))
     }
     return errs
 }
@@ -37,5 +59,24 @@ func NewDefaultDBConfig() *DBConfig {
     }
 }
 func (t *DBConfig) DSN() string {
-    return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", t.Username, t.Password, t.Host, t.Port, t.Database, "charset=utf8mb4&parseTime=true&loc=Asia%2fShanghai")
+    switch t.Driver {
+    case DBDriverPostgres:
+        sslMode := t.SSLMode
+        if sslMode == "" {
+            sslMode = "disable"
+        }
+        return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=Asia/Shanghai", t.Host, t.Port, t.Username, t.Password, t.Database, sslMode)
+    case DBDriverSQLite:
+        return t.Database + "?_pragma=busy_timeout(5000)"
+    default:
+        return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", t.Username, t.Password, t.Host, t.Port, t.Database, "charset=utf8mb4&parseTime=true&loc=Asia%2fShanghai")
+    }
+}
+
+// Address 返回用于日志的数据库地址，sqlite为文件路径
+func (t *DBConfig) Address() string {
+    if t.Driver == DBDriverSQLite {
+        return t.Database
+    }
+    return fmt.Sprintf("%s:%d/%s", t.Host, t.Port, t.Database)
 }
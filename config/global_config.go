@@ -17,8 +17,18 @@ type IConfig interface {
 }
 
 type GlobalConfig struct {
-	Port     int       `json:"port,omitempty" yaml:"port,omitempty"`
-	DBConfig *DBConfig `json:"db" yaml:"db"`
+	Port       int            `json:"port,omitempty" yaml:"port,omitempty"`
+	DBConfig   *DBConfig      `json:"db" yaml:"db"`
+	NATSConfig *NATSConfig    `json:"nats,omitempty" yaml:"nats,omitempty"`
+	Milvus     *MilvusConfig  `json:"milvus,omitempty" yaml:"milvus,omitempty"`
+	Article    *ArticleConfig `json:"article,omitempty" yaml:"article,omitempty"`
+	Images     *ImagesConfig  `json:"images,omitempty" yaml:"images,omitempty"`
+
+	VectorStore *VectorStoreConfig `json:"vectorStore,omitempty" yaml:"vectorStore,omitempty"`
+	Health      *HealthConfig      `json:"health,omitempty" yaml:"health,omitempty"`
+	Audit       *AuditConfig       `json:"audit,omitempty" yaml:"audit,omitempty"`
+	// 声明式管理的模型文件路径，为空时不同步
+	Models string `json:"models,omitempty" yaml:"models,omitempty"`
 }
 
 func (g *GlobalConfig) Validate() []error {
@@ -29,13 +39,62 @@ func (g *GlobalConfig) Validate() []error {
 	if es := g.DBConfig.Validate(); len(es) > 0 {
 		errs = append(errs, es...)
 	}
+	if g.NATSConfig.Enabled() {
+		if es := g.NATSConfig.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+	}
+	if g.Milvus != nil {
+		if es := g.Milvus.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+	}
+	if g.VectorStore != nil {
+		if es := g.VectorStore.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+		if g.VectorStore.Backend == VectorStoreMilvus && !g.Milvus.Enabled() {
+			errs = append(errs, errors.Errorf("向量存储使用Milvus，但没有配置Milvus服务地址"))
+		}
+		// 数据库向量存储的表结构和元数据过滤依赖mysql/sqlite的语法
+		if g.VectorStore.Backend == VectorStoreMySQL && g.DBConfig.Driver == DBDriverPostgres {
+			errs = append(errs, errors.Errorf("向量存储使用数据库后端时不支持postgres数据库驱动"))
+		}
+	}
+	if g.Article.Enabled() {
+		if es := g.Article.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+	}
+	if g.Images.Enabled() {
+		if es := g.Images.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+	}
+	if g.Health.Enabled() {
+		if es := g.Health.Validate(); len(es) > 0 {
+			errs = append(errs, es...)
+		}
+	}
+	if g.Models != "" {
+		if _, err := os.Stat(g.Models); err != nil {
+			errs = append(errs, errors.Errorf("模型文件不可用: %s", err.Error()))
+		}
+	}
 	return errs
 }
 
 func NewDefaultGlobalConfig() *GlobalConfig {
 	cfg := &GlobalConfig{
-		Port:     3000,
-		DBConfig: NewDefaultDBConfig(),
+		Port:       3000,
+		DBConfig:   NewDefaultDBConfig(),
+		NATSConfig: NewDefaultNATSConfig(),
+		Article:    NewDefaultArticleConfig(),
+		Images:     NewDefaultImagesConfig(),
+
+		VectorStore: NewDefaultVectorStoreConfig(),
+		Health:      NewDefaultHealthConfig(),
+		Audit:       NewDefaultAuditConfig(),
 	}
 	return cfg
 }
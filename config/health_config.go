@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// HealthConfig 模型健康检查的配置
+type HealthConfig struct {
+	// 探测间隔，单位秒，默认为0即不启动健康检查；探测会向上游发送真实请求并可能使down状态的模型拒绝请求，需要显式开启
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// 单次探测的超时时间，单位秒
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// 平均延迟超过该值时标记为degraded，单位毫秒
+	DegradedLatency int `json:"degradedLatency,omitempty" yaml:"degradedLatency,omitempty"`
+	// 连续失败达到该次数时标记为down
+	FailureThreshold int `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+	// 同时探测的模型数
+	Concurrency int `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+}
+
+func (t *HealthConfig) Validate() []error {
+	var errs = make([]error, 0)
+	if t.Timeout <= 0 {
+		errs = append(errs, errors.Errorf("健康检查的超时时间必须大于0"))
+	}
+	if t.Timeout > t.Interval {
+		errs = append(errs, errors.Errorf("健康检查的超时时间不能大于探测间隔"))
+	}
+	if t.DegradedLatency <= 0 {
+		errs = append(errs, errors.Errorf("健康检查的降级延迟阈值必须大于0"))
+	}
+	if t.FailureThreshold <= 0 {
+		errs = append(errs, errors.Errorf("健康检查的失败次数阈值必须大于0"))
+	}
+	if t.Concurrency <= 0 {
+		errs = append(errs, errors.Errorf("健康检查的并发数必须大于0"))
+	}
+	return errs
+}
+
+func NewDefaultHealthConfig() *HealthConfig {
+	return &HealthConfig{
+		Interval:         0,
+		Timeout:          10,
+		DegradedLatency:  3000,
+		FailureThreshold: 3,
+		Concurrency:      4,
+	}
+}
+
+// Enabled 是否启动健康检查
+func (t *HealthConfig) Enabled() bool {
+	return t != nil && t.Interval > 0
+}
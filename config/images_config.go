@@ -0,0 +1,46 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ImagesConfig 图片预处理的配置
+type ImagesConfig struct {
+	// 用于描述图片的多模态对话模型ID，为空时不启动图片预处理消费者
+	VisionModelID string `json:"visionModelId,omitempty" yaml:"visionModelId,omitempty"`
+	// 请求对话接口时使用的上游模型名称
+	VisionModel string `json:"visionModel,omitempty" yaml:"visionModel,omitempty"`
+	Prompt      string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	MaxTokens   int    `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	// 图片描述的嵌入模型，为空时只保存描述文本不生成向量
+	EmbeddingModelID string `json:"embeddingModelId,omitempty" yaml:"embeddingModelId,omitempty"`
+	EmbeddingModel   string `json:"embeddingModel,omitempty" yaml:"embeddingModel,omitempty"`
+	Collection       string `json:"collection,omitempty" yaml:"collection,omitempty"`
+}
+
+func (t *ImagesConfig) Validate() []error {
+	var errs = make([]error, 0)
+	if t.VisionModel == "" {
+		errs = append(errs, errors.Errorf("没有指定图片描述使用的上游模型名称"))
+	}
+	if t.Prompt == "" {
+		errs = append(errs, errors.Errorf("没有指定图片描述的提示词"))
+	}
+	if t.EmbeddingModelID != "" && t.Collection == "" {
+		errs = append(errs, errors.Errorf("没有指定图片描述的向量集合名称"))
+	}
+	return errs
+}
+
+func NewDefaultImagesConfig() *ImagesConfig {
+	return &ImagesConfig{
+		Prompt:     "请用中文详细描述这张图片的内容，包括主体、场景、文字和关键细节，只输出描述本身。",
+		MaxTokens:  512,
+		Collection: "image_captions",
+	}
+}
+
+// Enabled 是否配置了图片描述使用的模型
+func (t *ImagesConfig) Enabled() bool {
+	return t != nil && t.VisionModelID != ""
+}
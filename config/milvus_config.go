@@ -0,0 +1,25 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+type MilvusConfig struct {
+	Host     string `json:"host" yaml:"host"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	DBName   string `json:"dbname,omitempty" yaml:"dbname,omitempty"`
+}
+
+func (t *MilvusConfig) Validate() []error {
+	var errs = make([]error, 0)
+	if t.Host == "" {
+		errs = append(errs, errors.Errorf("没有指定Milvus服务地址"))
+	}
+	return errs
+}
+
+// Enabled 是否配置了Milvus
+func (t *MilvusConfig) Enabled() bool {
+	return t != nil && t.Host != ""
+}
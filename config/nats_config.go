@@ -0,0 +1,108 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NATSAccount NATS 连接账号，nkey/seed 和用户名/密码二选一
+type NATSAccount struct {
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Seed     string `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Nkey     string `json:"nkey,omitempty" yaml:"nkey,omitempty"`
+}
+
+type NATSConfig struct {
+	Endpoint   string `json:"endpoint" yaml:"endpoint"`
+	StreamName string `json:"streamName" yaml:"streamName"`
+	ClientID   string `json:"clientId" yaml:"clientId"`
+
+	// 对话请求的主题及消费者
+	Subject      string `json:"subject" yaml:"subject"`
+	ConsumerName string `json:"consumerName,omitempty" yaml:"consumerName,omitempty"`
+
+	ArticleSubject         string `json:"articleSubject,omitempty" yaml:"articleSubject,omitempty"`
+	ArticleConsumerName    string `json:"articleConsumerName,omitempty" yaml:"articleConsumerName,omitempty"`
+	ImagesProcSubject      string `json:"imagesProcSubject,omitempty" yaml:"imagesProcSubject,omitempty"`
+	ImagesProcConsumerName string `json:"imagesProcConsumerName,omitempty" yaml:"imagesProcConsumerName,omitempty"`
+	// 图片预处理通知所在的流，通知主题不在对话请求的流中，配置了ImagesProcSubject时必须指定且不能与StreamName相同；
+	// 处理完成的通知默认发布到ImagesProcReplySubject
+	ImagesProcStreamName   string `json:"imagesProcStreamName,omitempty" yaml:"imagesProcStreamName,omitempty"`
+	ImagesProcReplySubject string `json:"imagesProcReplySubject,omitempty" yaml:"imagesProcReplySubject,omitempty"`
+
+	// 消息最多投递次数、确认超时（秒）和并发处理数
+	MaxDeliver  int `json:"maxDeliver,omitempty" yaml:"maxDeliver,omitempty"`
+	AckWait     int `json:"ackWait,omitempty" yaml:"ackWait,omitempty"`
+	Concurrency int `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+
+	DefaultAccountName string                  `json:"defaultAccountName,omitempty" yaml:"defaultAccountName,omitempty"`
+	Account            map[string]*NATSAccount `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+func (t *NATSConfig) Validate() []error {
+	var errs = make([]error, 0)
+	if t.Endpoint == "" {
+		errs = append(errs, errors.Errorf("没有指定NATS服务地址"))
+	}
+	if t.StreamName == "" {
+		errs = append(errs, errors.Errorf("没有指定NATS JetStream流名称"))
+	}
+	if t.Subject == "" {
+		errs = append(errs, errors.Errorf("没有指定NATS对话请求主题"))
+	}
+	if strings.Contains(strings.ToLower(t.Subject), "{clientid}") && t.ClientID == "" {
+		errs = append(errs, errors.Errorf("NATS主题引用了{clientId}，但没有指定clientId"))
+	}
+	if t.ImagesProcSubject != "" {
+		if t.ImagesProcStreamName == "" {
+			errs = append(errs, errors.Errorf("配置了NATS图片预处理主题，但没有指定imagesProcStreamName"))
+		} else if t.ImagesProcStreamName == t.StreamName {
+			errs = append(errs, errors.Errorf("NATS图片预处理通知需要单独的流，imagesProcStreamName不能与streamName相同"))
+		}
+	}
+	if t.DefaultAccountName != "" {
+		if _, ok := t.Account[t.DefaultAccountName]; !ok {
+			errs = append(errs, errors.Errorf("NATS默认账号%s不存在", t.DefaultAccountName))
+		}
+	}
+	return errs
+}
+
+func NewDefaultNATSConfig() *NATSConfig {
+	return &NATSConfig{
+		ConsumerName:           "cmqa_chat_consumer",
+		ImagesProcReplySubject: "sudy.ai.notify.{clientId}.imagesProc.completed",
+		MaxDeliver:             5,
+		AckWait:                120,
+		Concurrency:            4,
+	}
+}
+
+// Enabled 是否配置了NATS，未配置服务地址时不启动消费者
+func (t *NATSConfig) Enabled() bool {
+	return t != nil && t.Endpoint != ""
+}
+
+// ResolveSubject 将主题模板中的{clientId}替换为配置的clientId，占位符不区分大小写
+func (t *NATSConfig) ResolveSubject(subject string) string {
+	for {
+		i := strings.Index(strings.ToLower(subject), "{clientid}")
+		if i < 0 {
+			return subject
+		}
+		subject = subject[:i] + t.ClientID + subject[i+len("{clientid}"):]
+	}
+}
+
+// DefaultAccount 返回默认账号，没有配置时返回nil
+func (t *NATSConfig) DefaultAccount() *NATSAccount {
+	return t.Account[t.DefaultAccountName]
+}
+
+// AckWaitDuration 返回消息确认超时时间
+func (t *NATSConfig) AckWaitDuration() time.Duration {
+	return time.Duration(t.AckWait) * time.Second
+}
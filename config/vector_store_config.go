@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// 向量存储支持的后端
+const (
+	VectorStoreMilvus = "milvus"
+	VectorStoreMemory = "memory"
+	VectorStoreMySQL  = "mysql"
+)
+
+// VectorStoreConfig 向量存储的配置，Backend为空时配置了Milvus则使用Milvus，否则使用内存存储
+type VectorStoreConfig struct {
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+}
+
+func (t *VectorStoreConfig) Validate() []error {
+	var errs = make([]error, 0)
+	switch t.Backend {
+	case "", VectorStoreMilvus, VectorStoreMemory, VectorStoreMySQL:
+	default:
+		errs = append(errs, errors.Errorf("不支持的向量存储后端%s，可选值: milvus、memory、mysql", t.Backend))
+	}
+	return errs
+}
+
+func NewDefaultVectorStoreConfig() *VectorStoreConfig {
+	return &VectorStoreConfig{}
+}
+
+// ResolveBackend 返回实际使用的后端
+func (t *VectorStoreConfig) ResolveBackend(milvus *MilvusConfig) string {
+	if t != nil && t.Backend != "" {
+		return t.Backend
+	}
+	if milvus.Enabled() {
+		return VectorStoreMilvus
+	}
+	return VectorStoreMemory
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"myapi/config"
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Logger 记录审计事件，写入t_audit_log表，配置了文件时同时追加一行JSON
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewLogger 创建审计日志记录器，配置的文件不存在时自动创建
+func NewLogger(cfg *config.AuditConfig) (*Logger, error) {
+	l := &Logger{}
+	if cfg != nil && cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, errors.Wrap(err, "打开审计日志文件失败")
+		}
+		l.file = file
+	}
+	return l, nil
+}
+
+// Record 写入一条审计事件，写入失败只记录日志，不影响请求
+func (l *Logger) Record(ctx context.Context, event *models.AuditLog) {
+	if err := db.GetDBWithContext(ctx).Create(event).Error; err != nil {
+		zap.S().Errorf("写入审计日志失败: %v", err)
+	}
+	if l.file == nil {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		zap.S().Errorf("序列化审计日志失败: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		zap.S().Errorf("写入审计日志文件失败: %v", err)
+	}
+}
+
+// Close 关闭审计日志文件
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,65 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+
+	"myapi/config"
+	"myapi/pkg/ingest"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ArticleConsumer 从文章主题消费文章，切分、向量化后写入Milvus
+type ArticleConsumer struct {
+	*Consumer
+	ingester *ingest.ArticleIngester
+}
+
+// NewArticleConsumer 创建文章入库消费者
+func NewArticleConsumer(nc *nats.Conn, cfg *config.NATSConfig, ingester *ingest.ArticleIngester) (*ArticleConsumer, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	ac := &ArticleConsumer{ingester: ingester}
+	ac.Consumer = NewConsumer(js, ConsumerOptions{
+		Stream:         cfg.StreamName,
+		StreamSubjects: streamSubjects(cfg, cfg.StreamName),
+		Durable:        cfg.ArticleConsumerName,
+		FilterSubject:  cfg.ResolveSubject(cfg.ArticleSubject),
+		MaxDeliver:     cfg.MaxDeliver,
+		AckWait:        cfg.AckWaitDuration(),
+		Concurrency:    cfg.Concurrency,
+	}, ac.handle, nil)
+	return ac, nil
+}
+
+func (ac *ArticleConsumer) handle(ctx context.Context, msg jetstream.Msg) error {
+	var article models.Article
+	if err := json.Unmarshal(msg.Data(), &article); err != nil {
+		return Permanent(errors.Wrap(err, "文章消息解析失败"))
+	}
+	if err := binding.Validator.ValidateStruct(&article); err != nil {
+		return Permanent(errors.Wrap(err, "文章消息参数错误"))
+	}
+
+	// 长文章向量化耗时较长，每批完成后延长确认时间
+	n, err := ac.ingester.Ingest(ctx, &article, func() {
+		_ = msg.InProgress()
+	})
+	if err != nil {
+		if ingest.IsPermanent(err) || llm.IsPermanentError(err) {
+			return Permanent(err)
+		}
+		return err
+	}
+	zap.S().Infof("文章[%s]入库成功，分块数: %d", article.ArticleID, n)
+	return nil
+}
@@ -0,0 +1,158 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"myapi/config"
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ReplyToHeader 消息头中的回复主题，JetStream 消息本身的 reply 为 ack 主题
+const ReplyToHeader = "Reply-To"
+
+// chatFailure 处理失败的对话请求，携带回复给调用方的状态码
+type chatFailure struct {
+	status int
+	err    error
+}
+
+func (e *chatFailure) Error() string {
+	return e.err.Error()
+}
+
+func (e *chatFailure) Unwrap() error {
+	return e.err
+}
+
+// ChatConsumer 从对话请求主题消费消息，调用对应的模型并把结果发布到回复主题
+type ChatConsumer struct {
+	*Consumer
+	nc             *nats.Conn
+	articleSubject string
+}
+
+// NewChatConsumer 创建对话请求消费者
+func NewChatConsumer(nc *nats.Conn, cfg *config.NATSConfig) (*ChatConsumer, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	cc := &ChatConsumer{nc: nc}
+	if cfg.ArticleSubject != "" {
+		cc.articleSubject = cfg.ResolveSubject(cfg.ArticleSubject)
+	}
+	cc.Consumer = NewConsumer(js, ConsumerOptions{
+		Stream:         cfg.StreamName,
+		StreamSubjects: streamSubjects(cfg, cfg.StreamName),
+		Durable:        cfg.ConsumerName,
+		FilterSubject:  cfg.ResolveSubject(cfg.Subject),
+		MaxDeliver:     cfg.MaxDeliver,
+		AckWait:        cfg.AckWaitDuration(),
+		Concurrency:    cfg.Concurrency,
+	}, cc.handle, cc.fail)
+	return cc, nil
+}
+
+func (cc *ChatConsumer) handle(ctx context.Context, msg jetstream.Msg) error {
+	// 文章主题与对话主题的通配符重叠，由文章消费者处理
+	if cc.articleSubject != "" && msg.Subject() == cc.articleSubject {
+		return nil
+	}
+
+	var req models.AsyncChatRequest
+	if err := json.Unmarshal(msg.Data(), &req); err != nil {
+		return Permanent(&chatFailure{status: http.StatusBadRequest, err: errors.New("参数错误: " + err.Error())})
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return Permanent(&chatFailure{status: http.StatusBadRequest, err: errors.New("参数错误: " + err.Error())})
+	}
+
+	database := db.GetDBWithContext(ctx)
+	// 包括已删除的模型，由llm按模型状态拒绝
+	var model models.Model
+	if err := database.Unscoped().Where("model_id = ?", req.ModelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Permanent(&chatFailure{status: http.StatusNotFound, err: errors.New("模型不存在")})
+		}
+		return &chatFailure{status: http.StatusInternalServerError, err: errors.New("查询模型失败: " + err.Error())}
+	}
+
+	messages, err := llm.FitContext(ctx, &model, req.Model, req.Messages, models.ModelLoader(database))
+	if err != nil {
+		var tooLong *llm.ContextTooLongError
+		if errors.As(err, &tooLong) {
+			return Permanent(&chatFailure{status: http.StatusBadRequest, err: err})
+		}
+		return &chatFailure{status: http.StatusInternalServerError, err: errors.New("处理上下文失败: " + err.Error())}
+	}
+	req.Messages = messages
+
+	resp, err := llm.Chat(ctx, &model, &req.ChatRequest)
+	if err != nil {
+		if llm.IsPermanentError(err) {
+			status := http.StatusInternalServerError
+			var upstream *llm.UpstreamError
+			if errors.As(err, &upstream) {
+				status = upstream.StatusCode
+			} else if rejected, ok := llm.RejectionStatus(err); ok {
+				status = rejected
+			}
+			return Permanent(&chatFailure{status: status, err: err})
+		}
+		if errors.Is(err, llm.ErrModelUnavailable) {
+			return &chatFailure{status: http.StatusServiceUnavailable, err: err}
+		}
+		return &chatFailure{status: http.StatusBadGateway, err: err}
+	}
+
+	cc.reply(msg, &req, &models.AsyncChatReply{
+		RequestID:   req.RequestID,
+		APIResponse: *models.NewSuccessResponse(resp, "对话成功"),
+	})
+	return nil
+}
+
+// fail 请求被放弃时向调用方回复错误
+func (cc *ChatConsumer) fail(_ context.Context, msg jetstream.Msg, err error) {
+	var req models.AsyncChatRequest
+	_ = json.Unmarshal(msg.Data(), &req)
+	status := http.StatusInternalServerError
+	var failure *chatFailure
+	if errors.As(err, &failure) {
+		status = failure.status
+	}
+	cc.reply(msg, &req, &models.AsyncChatReply{
+		RequestID:   req.RequestID,
+		APIResponse: *models.NewErrorResponse(status, err.Error()),
+	})
+}
+
+func (cc *ChatConsumer) reply(msg jetstream.Msg, req *models.AsyncChatRequest, reply *models.AsyncChatReply) {
+	subject := req.ReplyTo
+	if subject == "" && msg.Headers() != nil {
+		subject = msg.Headers().Get(ReplyToHeader)
+	}
+	if subject == "" {
+		zap.S().Warnf("对话请求[%s]没有指定回复主题，结果被丢弃", req.RequestID)
+		return
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		zap.S().Errorf("对话结果序列化失败: %v", err)
+		return
+	}
+	if err := cc.nc.Publish(subject, data); err != nil {
+		zap.S().Errorf("对话结果发布到%s失败: %v", subject, err)
+	}
+}
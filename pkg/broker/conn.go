@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"time"
+
+	"myapi/config"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Connect 按配置连接NATS，服务不可达时在后台持续重连，不阻塞启动
+func Connect(cfg *config.NATSConfig) (*nats.Conn, error) {
+	opts := []nats.Option{
+		nats.Name("myapi"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2 * time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				zap.S().Warnf("NATS连接断开: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			zap.S().Infof("NATS已重新连接: %s", nc.ConnectedUrl())
+		}),
+	}
+	if account := cfg.DefaultAccount(); account != nil {
+		switch {
+		case account.Seed != "":
+			kp, err := nkeys.FromSeed([]byte(account.Seed))
+			if err != nil {
+				return nil, errors.Wrap(err, "解析NATS账号seed失败")
+			}
+			publicKey, err := kp.PublicKey()
+			if err != nil {
+				return nil, errors.Wrap(err, "解析NATS账号nkey失败")
+			}
+			if account.Nkey != "" && account.Nkey != publicKey {
+				zap.S().Warnf("NATS账号%s配置的nkey与seed不匹配，使用seed对应的nkey", cfg.DefaultAccountName)
+			}
+			opts = append(opts, nats.Nkey(publicKey, kp.Sign))
+		case account.Username != "":
+			opts = append(opts, nats.UserInfo(account.Username, account.Password))
+		}
+	}
+	nc, err := nats.Connect(cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "连接NATS失败")
+	}
+	return nc, nil
+}
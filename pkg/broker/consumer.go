@@ -0,0 +1,198 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// setupRetryInterval 创建流或消费者失败后的重试间隔
+const setupRetryInterval = 5 * time.Second
+
+// Handler 处理一条消息，返回nil时确认消息，返回PermanentError时终止投递，其他错误稍后重新投递
+type Handler func(ctx context.Context, msg jetstream.Msg) error
+
+// FailureHandler 消息被放弃（永久错误或超过最大投递次数）时的回调
+type FailureHandler func(ctx context.Context, msg jetstream.Msg, err error)
+
+// PermanentError 无法通过重试恢复的错误
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent 将错误标记为无法通过重试恢复
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// ConsumerOptions JetStream 持久化消费者的配置
+type ConsumerOptions struct {
+	Stream string
+	// 流不存在时创建流使用的主题，应包含使用同一个流的全部消费者的主题，为空时只使用FilterSubject
+	StreamSubjects []string
+	Durable        string
+	FilterSubject  string
+	MaxDeliver     int
+	AckWait        time.Duration
+	Concurrency    int
+}
+
+// Consumer JetStream 持久化消费者，按并发数处理消息并负责 ack/nak/term
+type Consumer struct {
+	js        jetstream.JetStream
+	opts      ConsumerOptions
+	handler   Handler
+	onFailure FailureHandler
+}
+
+// NewConsumer 创建持久化消费者，onFailure 可以为nil
+func NewConsumer(js jetstream.JetStream, opts ConsumerOptions, handler Handler, onFailure FailureHandler) *Consumer {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return &Consumer{
+		js:        js,
+		opts:      opts,
+		handler:   handler,
+		onFailure: onFailure,
+	}
+}
+
+// Run 创建或更新消费者并持续消费，直到ctx结束；NATS不可用时会持续重试
+func (c *Consumer) Run(ctx context.Context) error {
+	var cons jetstream.Consumer
+	for {
+		var err error
+		if cons, err = c.setup(ctx); err == nil {
+			break
+		}
+		zap.S().Errorf("NATS消费者[%s]初始化失败，%s后重试: %v", c.opts.Durable, setupRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(setupRetryInterval):
+		}
+	}
+
+	sem := make(chan struct{}, c.opts.Concurrency)
+	var wg sync.WaitGroup
+	cc, err := cons.Consume(func(msg jetstream.Msg) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.handle(ctx, msg)
+		}()
+	}, jetstream.PullMaxMessages(c.opts.Concurrency), jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		zap.S().Warnf("NATS消费者[%s]拉取消息错误: %v", c.opts.Durable, err)
+	}))
+	if err != nil {
+		return err
+	}
+	zap.S().Debugf("NATS消费者[%s]已经运行，订阅主题: %s", c.opts.Durable, c.opts.FilterSubject)
+
+	<-ctx.Done()
+	cc.Stop()
+	wg.Wait()
+	zap.S().Debugf("NATS消费者[%s]已经关闭...", c.opts.Durable)
+	return nil
+}
+
+// setup 确保流存在并创建或更新持久化消费者；流不存在时以StreamSubjects创建，已存在的流必须包含消费者的主题
+func (c *Consumer) setup(ctx context.Context) (jetstream.Consumer, error) {
+	stream, err := c.js.Stream(ctx, c.opts.Stream)
+	switch {
+	case err == nil:
+		if subjects := stream.CachedInfo().Config.Subjects; !streamCovers(subjects, c.opts.FilterSubject) {
+			return nil, fmt.Errorf("NATS流%s的主题%v不包含消费者的主题%s，请为该主题配置单独的流或修改流的主题", c.opts.Stream, subjects, c.opts.FilterSubject)
+		}
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		subjects := c.opts.StreamSubjects
+		if len(subjects) == 0 {
+			subjects = []string{c.opts.FilterSubject}
+		}
+		if _, err := c.js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     c.opts.Stream,
+			Subjects: subjects,
+		}); err != nil {
+			return nil, err
+		}
+		zap.S().Infof("已创建NATS流: %s，主题: %v", c.opts.Stream, subjects)
+	default:
+		return nil, err
+	}
+	return c.js.CreateOrUpdateConsumer(ctx, c.opts.Stream, jetstream.ConsumerConfig{
+		Durable:       c.opts.Durable,
+		FilterSubject: c.opts.FilterSubject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       c.opts.AckWait,
+		MaxDeliver:    c.opts.MaxDeliver,
+		MaxAckPending: c.opts.Concurrency * 2,
+	})
+}
+
+func (c *Consumer) handle(ctx context.Context, msg jetstream.Msg) {
+	err := c.handler(ctx, msg)
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			zap.S().Warnf("NATS消息确认失败[%s]: %v", msg.Subject(), err)
+		}
+		return
+	}
+
+	var permanent *PermanentError
+	if errors.As(err, &permanent) || c.lastDelivery(msg) {
+		zap.S().Errorf("NATS消息处理失败，不再重试[%s]: %v", msg.Subject(), err)
+		if c.onFailure != nil {
+			c.onFailure(ctx, msg, err)
+		}
+		if err := msg.Term(); err != nil {
+			zap.S().Warnf("NATS消息终止失败[%s]: %v", msg.Subject(), err)
+		}
+		return
+	}
+
+	delay := c.retryDelay(msg)
+	zap.S().Warnf("NATS消息处理失败，%s后重试[%s]: %v", delay, msg.Subject(), err)
+	if err := msg.NakWithDelay(delay); err != nil {
+		zap.S().Warnf("NATS消息nak失败[%s]: %v", msg.Subject(), err)
+	}
+}
+
+// lastDelivery 判断是否已达到最大投递次数
+func (c *Consumer) lastDelivery(msg jetstream.Msg) bool {
+	if c.opts.MaxDeliver <= 0 {
+		return false
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return false
+	}
+	return int(meta.NumDelivered) >= c.opts.MaxDeliver
+}
+
+// retryDelay 按投递次数指数退避，最长1分钟
+func (c *Consumer) retryDelay(msg jetstream.Msg) time.Duration {
+	delivered := uint64(1)
+	if meta, err := msg.Metadata(); err == nil && meta.NumDelivered > 0 {
+		delivered = meta.NumDelivered
+	}
+	delay := time.Second << min(delivered-1, 6)
+	return min(delay, time.Minute)
+}
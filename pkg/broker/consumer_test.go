@@ -0,0 +1,376 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"myapi/config"
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// runJetStream 启动开启JetStream的内嵌NATS服务并连接
+func runJetStream(t *testing.T) (*nats.Conn, jetstream.JetStream) {
+	t.Helper()
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s := natsserver.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("连接NATS失败: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("创建JetStream失败: %v", err)
+	}
+	return nc, js
+}
+
+// runConsumer 在后台运行消费者并等待消费者创建完成，测试结束时停止
+func runConsumer(t *testing.T, js jetstream.JetStream, c *Consumer) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := c.Run(ctx); err != nil {
+			t.Errorf("消费者运行失败: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitFor(t, "消费者创建完成", func() bool {
+		_, err := js.Consumer(ctx, c.opts.Stream, c.opts.Durable)
+		return err == nil
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitSettled 等待消费者没有未投递和未确认的消息
+func waitSettled(t *testing.T, js jetstream.JetStream, stream, durable string) {
+	t.Helper()
+	waitFor(t, "消息全部确认", func() bool {
+		cons, err := js.Consumer(context.Background(), stream, durable)
+		if err != nil {
+			return false
+		}
+		info, err := cons.Info(context.Background())
+		return err == nil && info.NumPending == 0 && info.NumAckPending == 0 && info.NumRedelivered == 0
+	})
+}
+
+// recorder 记录处理函数每次被调用的时间和投递次数
+type recorder struct {
+	mu        sync.Mutex
+	calls     []time.Time
+	delivered []uint64
+	failures  []error
+}
+
+func (r *recorder) record(msg jetstream.Msg) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, time.Now())
+	if meta, err := msg.Metadata(); err == nil {
+		r.delivered = append(r.delivered, meta.NumDelivered)
+	}
+	return len(r.calls)
+}
+
+func (r *recorder) onFailure(_ context.Context, _ jetstream.Msg, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, err)
+}
+
+func (r *recorder) snapshot() ([]time.Time, []uint64, []error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls), slices.Clone(r.delivered), slices.Clone(r.failures)
+}
+
+func testOptions(durable string, maxDeliver int) ConsumerOptions {
+	return ConsumerOptions{
+		Stream:         "TEST",
+		StreamSubjects: []string{"test.req.>", "test.notify"},
+		Durable:        durable,
+		FilterSubject:  "test.req.>",
+		MaxDeliver:     maxDeliver,
+		AckWait:        10 * time.Second,
+		Concurrency:    2,
+	}
+}
+
+func TestConsumerAck(t *testing.T) {
+	_, js := runJetStream(t)
+	rec := &recorder{}
+	c := NewConsumer(js, testOptions("ack", 3), func(_ context.Context, msg jetstream.Msg) error {
+		rec.record(msg)
+		return nil
+	}, rec.onFailure)
+	runConsumer(t, js, c)
+
+	// 流不存在时以全部配置的主题创建
+	stream, err := js.Stream(context.Background(), "TEST")
+	if err != nil {
+		t.Fatalf("查询流失败: %v", err)
+	}
+	if subjects := stream.CachedInfo().Config.Subjects; !slices.Equal(subjects, []string{"test.req.>", "test.notify"}) {
+		t.Errorf("流的主题为%v，期望包含全部配置的主题", subjects)
+	}
+
+	if _, err := js.Publish(context.Background(), "test.req.a", []byte("hello")); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	waitSettled(t, js, "TEST", "ack")
+	calls, _, failures := rec.snapshot()
+	if len(calls) != 1 || len(failures) != 0 {
+		t.Errorf("处理了%d次，失败回调%d次，期望处理1次且没有失败", len(calls), len(failures))
+	}
+}
+
+func TestConsumerNakWithBackoff(t *testing.T) {
+	_, js := runJetStream(t)
+	rec := &recorder{}
+	c := NewConsumer(js, testOptions("nak", 3), func(_ context.Context, msg jetstream.Msg) error {
+		if rec.record(msg) == 1 {
+			return errors.New("临时错误")
+		}
+		return nil
+	}, rec.onFailure)
+	runConsumer(t, js, c)
+
+	if _, err := js.Publish(context.Background(), "test.req.a", []byte("hello")); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	waitFor(t, "重新投递", func() bool {
+		calls, _, _ := rec.snapshot()
+		return len(calls) == 2
+	})
+	waitSettled(t, js, "TEST", "nak")
+
+	calls, delivered, failures := rec.snapshot()
+	if len(calls) != 2 || len(failures) != 0 {
+		t.Fatalf("处理了%d次，失败回调%d次，期望重试1次后成功", len(calls), len(failures))
+	}
+	if !slices.Equal(delivered, []uint64{1, 2}) {
+		t.Errorf("投递次数为%v，期望[1 2]", delivered)
+	}
+	// 第一次失败后按退避等待1秒再投递
+	if gap := calls[1].Sub(calls[0]); gap < 900*time.Millisecond {
+		t.Errorf("重新投递间隔为%s，期望至少1秒", gap)
+	}
+}
+
+func TestConsumerMaxDeliverTerm(t *testing.T) {
+	_, js := runJetStream(t)
+	rec := &recorder{}
+	c := NewConsumer(js, testOptions("term", 2), func(_ context.Context, msg jetstream.Msg) error {
+		rec.record(msg)
+		return errors.New("一直失败")
+	}, rec.onFailure)
+	runConsumer(t, js, c)
+
+	if _, err := js.Publish(context.Background(), "test.req.a", []byte("hello")); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	waitFor(t, "放弃消息", func() bool {
+		_, _, failures := rec.snapshot()
+		return len(failures) == 1
+	})
+	waitSettled(t, js, "TEST", "term")
+
+	calls, delivered, failures := rec.snapshot()
+	if len(calls) != 2 || !slices.Equal(delivered, []uint64{1, 2}) {
+		t.Errorf("投递次数为%v，期望达到最大投递次数2后终止", delivered)
+	}
+	if len(failures) != 1 || failures[0].Error() != "一直失败" {
+		t.Errorf("失败回调为%v，期望收到最后一次的错误", failures)
+	}
+}
+
+func TestConsumerPermanentErrorTerm(t *testing.T) {
+	_, js := runJetStream(t)
+	rec := &recorder{}
+	c := NewConsumer(js, testOptions("permanent", 5), func(_ context.Context, msg jetstream.Msg) error {
+		rec.record(msg)
+		return Permanent(errors.New("参数错误"))
+	}, rec.onFailure)
+	runConsumer(t, js, c)
+
+	if _, err := js.Publish(context.Background(), "test.req.a", []byte("hello")); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	waitFor(t, "放弃消息", func() bool {
+		_, _, failures := rec.snapshot()
+		return len(failures) == 1
+	})
+	waitSettled(t, js, "TEST", "permanent")
+	if calls, _, _ := rec.snapshot(); len(calls) != 1 {
+		t.Errorf("处理了%d次，永久错误期望不再重试", len(calls))
+	}
+}
+
+func TestConsumerStreamMissingSubject(t *testing.T) {
+	_, js := runJetStream(t)
+	if _, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "TEST", Subjects: []string{"test.other.>"}}); err != nil {
+		t.Fatalf("创建流失败: %v", err)
+	}
+	c := NewConsumer(js, testOptions("missing", 3), func(context.Context, jetstream.Msg) error { return nil }, nil)
+	_, err := c.setup(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "test.req.>") {
+		t.Errorf("流不包含消费者的主题时返回%v，期望说明缺少的主题", err)
+	}
+}
+
+func TestStreamSubjects(t *testing.T) {
+	cfg := config.NewDefaultNATSConfig()
+	cfg.StreamName = "cmqa_stream"
+	cfg.ClientID = "c1"
+	cfg.Subject = "sudy.ai.req.{clientId}.>"
+	cfg.ArticleSubject = "sudy.ai.req.{clientId}.article"
+	cfg.ImagesProcSubject = "sudy.ai.notify.{clientId}.imagesProc"
+	cfg.ImagesProcStreamName = "cmqa_images"
+
+	if got := streamSubjects(cfg, "cmqa_stream"); !slices.Equal(got, []string{"sudy.ai.req.c1.>"}) {
+		t.Errorf("对话流的主题为%v，文章主题已被对话主题覆盖", got)
+	}
+	if got := streamSubjects(cfg, "cmqa_images"); !slices.Equal(got, []string{"sudy.ai.notify.c1.imagesProc"}) {
+		t.Errorf("图片流的主题为%v", got)
+	}
+}
+
+func TestSubjectCovers(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"a.b", "a.b", true},
+		{"a.b", "a.c", false},
+		{"a.*", "a.b", true},
+		{"a.*", "a.b.c", false},
+		{"a.>", "a.b.c", true},
+		{"a.>", "a", false},
+		{"a.>", "a.*", true},
+		{"a.*", "a.>", false},
+		{"a.b", "a.*", false},
+		{"a.*.c", "a.*.c", true},
+	}
+	for _, tt := range tests {
+		if got := subjectCovers(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("subjectCovers(%q, %q) = %v，期望%v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestChatConsumerReply(t *testing.T) {
+	dbtest.Setup(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"index": 0, "message": map[string]any{"role": "assistant", "content": "你好"}}},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+	model := &models.Model{
+		ModelID:  uuid.New().String(),
+		Name:     "broker-test-" + uuid.New().String(),
+		Endpoint: upstream.URL + "/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     models.ModelTypeChat,
+		Enabled:  true,
+	}
+	if err := db.GetDB().Create(model).Error; err != nil {
+		t.Fatalf("创建模型失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.GetDB().Unscoped().Delete(&models.Model{}, "model_id = ?", model.ModelID)
+	})
+
+	nc, js := runJetStream(t)
+	cfg := config.NewDefaultNATSConfig()
+	cfg.StreamName = "CHAT"
+	cfg.Subject = "chat.req"
+	cfg.AckWait = 10
+	cc, err := NewChatConsumer(nc, cfg)
+	if err != nil {
+		t.Fatalf("创建对话消费者失败: %v", err)
+	}
+	runConsumer(t, js, cc.Consumer)
+
+	sub, err := nc.SubscribeSync("chat.reply")
+	if err != nil {
+		t.Fatalf("订阅回复主题失败: %v", err)
+	}
+	send := func(req models.AsyncChatRequest) models.AsyncChatReply {
+		t.Helper()
+		data, _ := json.Marshal(req)
+		msg := nats.NewMsg("chat.req")
+		msg.Data = data
+		msg.Header.Set(ReplyToHeader, "chat.reply")
+		if _, err := js.PublishMsg(context.Background(), msg); err != nil {
+			t.Fatalf("发布对话请求失败: %v", err)
+		}
+		replyMsg, err := sub.NextMsg(10 * time.Second)
+		if err != nil {
+			t.Fatalf("等待回复失败: %v", err)
+		}
+		var reply models.AsyncChatReply
+		if err := json.Unmarshal(replyMsg.Data, &reply); err != nil {
+			t.Fatalf("解析回复失败: %v", err)
+		}
+		return reply
+	}
+	messages := []models.ChatMessage{{Role: "user", Content: "你好"}}
+
+	reply := send(models.AsyncChatRequest{
+		RequestID:   "ok",
+		ModelID:     model.ModelID,
+		ChatRequest: models.ChatRequest{Model: "gpt-test", Messages: messages},
+	})
+	if reply.RequestID != "ok" || reply.Status != http.StatusOK {
+		t.Errorf("对话成功时回复为%+v", reply)
+	}
+
+	// 模型不存在时不重试，直接回复404
+	reply = send(models.AsyncChatRequest{
+		RequestID:   "missing",
+		ModelID:     uuid.New().String(),
+		ChatRequest: models.ChatRequest{Model: "gpt-test", Messages: messages},
+	})
+	if reply.RequestID != "missing" || reply.Status != http.StatusNotFound {
+		t.Errorf("模型不存在时回复为%+v，期望404", reply)
+	}
+	waitSettled(t, js, "CHAT", cfg.ConsumerName)
+}
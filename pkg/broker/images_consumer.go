@@ -0,0 +1,103 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+
+	"myapi/config"
+	"myapi/pkg/ingest"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ImagesProcConsumer 消费图片预处理通知，描述图片后发布完成通知
+type ImagesProcConsumer struct {
+	*Consumer
+	nc           *nats.Conn
+	captioner    *ingest.ImageCaptioner
+	replySubject string
+}
+
+// NewImagesProcConsumer 创建图片预处理消费者
+func NewImagesProcConsumer(nc *nats.Conn, cfg *config.NATSConfig, captioner *ingest.ImageCaptioner) (*ImagesProcConsumer, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	ic := &ImagesProcConsumer{
+		nc:           nc,
+		captioner:    captioner,
+		replySubject: cfg.ResolveSubject(cfg.ImagesProcReplySubject),
+	}
+	ic.Consumer = NewConsumer(js, ConsumerOptions{
+		Stream:         cfg.ImagesProcStreamName,
+		StreamSubjects: streamSubjects(cfg, cfg.ImagesProcStreamName),
+		Durable:        cfg.ImagesProcConsumerName,
+		FilterSubject:  cfg.ResolveSubject(cfg.ImagesProcSubject),
+		MaxDeliver:     cfg.MaxDeliver,
+		AckWait:        cfg.AckWaitDuration(),
+		Concurrency:    cfg.Concurrency,
+	}, ic.handle, ic.fail)
+	return ic, nil
+}
+
+func (ic *ImagesProcConsumer) handle(ctx context.Context, msg jetstream.Msg) error {
+	var n models.ImageProcNotification
+	if err := json.Unmarshal(msg.Data(), &n); err != nil {
+		return Permanent(errors.Wrap(err, "图片预处理通知解析失败"))
+	}
+	if err := binding.Validator.ValidateStruct(&n); err != nil {
+		return Permanent(errors.Wrap(err, "图片预处理通知参数错误"))
+	}
+
+	result, err := ic.captioner.Process(ctx, &n, func() {
+		_ = msg.InProgress()
+	})
+	if err != nil {
+		if result == nil && (ingest.IsPermanent(err) || llm.IsPermanentError(err)) {
+			return Permanent(err)
+		}
+		// 存在可重试的错误，重新投递时已成功的图片会直接复用结果
+		return err
+	}
+	ic.publish(msg, &n, result)
+	zap.S().Infof("图片预处理任务[%s]完成，状态: %s", n.TaskID, result.Status)
+	return nil
+}
+
+// fail 任务被放弃时发布失败的完成通知
+func (ic *ImagesProcConsumer) fail(ctx context.Context, msg jetstream.Msg, err error) {
+	var n models.ImageProcNotification
+	if e := json.Unmarshal(msg.Data(), &n); e != nil {
+		return
+	}
+	ic.publish(msg, &n, ic.captioner.Result(ctx, &n, err))
+}
+
+func (ic *ImagesProcConsumer) publish(msg jetstream.Msg, n *models.ImageProcNotification, result *models.ImageProcResult) {
+	subject := n.ReplyTo
+	if subject == "" && msg.Headers() != nil {
+		subject = msg.Headers().Get(ReplyToHeader)
+	}
+	if subject == "" {
+		subject = ic.replySubject
+	}
+	if subject == "" {
+		zap.S().Warnf("图片预处理任务[%s]没有指定完成通知主题，结果被丢弃", n.TaskID)
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		zap.S().Errorf("图片预处理结果序列化失败: %v", err)
+		return
+	}
+	if err := ic.nc.Publish(subject, data); err != nil {
+		zap.S().Errorf("图片预处理结果发布到%s失败: %v", subject, err)
+	}
+}
@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"slices"
+	"strings"
+
+	"myapi/config"
+)
+
+// streamSubjects 返回配置中使用指定流的全部主题，流不存在时以这些主题创建流
+func streamSubjects(cfg *config.NATSConfig, stream string) []string {
+	var subjects []string
+	add := func(subject string) {
+		if subject != "" {
+			subjects = append(subjects, cfg.ResolveSubject(subject))
+		}
+	}
+	if cfg.StreamName == stream {
+		add(cfg.Subject)
+		add(cfg.ArticleSubject)
+	}
+	if cfg.ImagesProcStreamName == stream {
+		add(cfg.ImagesProcSubject)
+	}
+
+	// 同一个流的主题不能重叠，去掉被其他主题覆盖的主题
+	result := make([]string, 0, len(subjects))
+	for i, subject := range subjects {
+		covered := false
+		for j, other := range subjects {
+			if i == j {
+				continue
+			}
+			// 两个主题互相覆盖（完全相同）时只保留第一个
+			if subjectCovers(other, subject) && (!subjectCovers(subject, other) || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, subject)
+		}
+	}
+	return result
+}
+
+// streamCovers 判断流的主题是否包含消费者的过滤主题
+func streamCovers(streamSubjects []string, subject string) bool {
+	return slices.ContainsFunc(streamSubjects, func(s string) bool {
+		return subjectCovers(s, subject)
+	})
+}
+
+// subjectCovers 判断主题pattern匹配的消息是否包含subject匹配的全部消息，两者都可以带通配符
+func subjectCovers(pattern, subject string) bool {
+	p := strings.Split(pattern, ".")
+	s := strings.Split(subject, ".")
+	for i, token := range p {
+		if token == ">" {
+			return len(s) > i
+		}
+		if i >= len(s) || s[i] == ">" {
+			return false
+		}
+		if token != "*" && token != s[i] {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
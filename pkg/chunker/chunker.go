@@ -0,0 +1,91 @@
+package chunker
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// separators 依次尝试的切分符：段落、换行、句子、空白
+var separators = []string{"\n\n", "\n", "。", "！", "？", ". ", "! ", "? ", "；", "; ", "，", ", ", " "}
+
+// Split 将文本切分为不超过size个字符的分块，相邻分块重叠overlap个字符；
+// 优先在段落、句子等自然边界处切分，找不到边界时按字符硬切
+func Split(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" || size <= 0 {
+		return nil
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	pieces := splitPieces(text, size, 0)
+
+	var chunks []string
+	var current []rune
+	for _, piece := range pieces {
+		p := []rune(piece)
+		if len(current) > 0 && len(current)+len(p) > size {
+			chunks = appendChunk(chunks, current)
+			current = tail(current, overlap)
+			// 重叠部分加上新片段仍然超长时丢弃重叠部分
+			if len(current)+len(p) > size {
+				current = nil
+			}
+		}
+		current = append(current, p...)
+	}
+	return appendChunk(chunks, current)
+}
+
+// splitPieces 递归地按分隔符切分，保证每个片段不超过size个字符，分隔符保留在片段末尾
+func splitPieces(text string, size, level int) []string {
+	if utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	if level >= len(separators) {
+		return hardSplit(text, size)
+	}
+	sep := separators[level]
+	parts := strings.SplitAfter(text, sep)
+	if len(parts) == 1 {
+		return splitPieces(text, size, level+1)
+	}
+	var pieces []string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		pieces = append(pieces, splitPieces(part, size, level+1)...)
+	}
+	return pieces
+}
+
+func hardSplit(text string, size int) []string {
+	runes := []rune(text)
+	var pieces []string
+	for len(runes) > size {
+		pieces = append(pieces, string(runes[:size]))
+		runes = runes[size:]
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+func tail(runes []rune, n int) []rune {
+	if n <= 0 {
+		return nil
+	}
+	if len(runes) <= n {
+		return append([]rune{}, runes...)
+	}
+	return append([]rune{}, runes[len(runes)-n:]...)
+}
+
+func appendChunk(chunks []string, runes []rune) []string {
+	if chunk := strings.TrimSpace(string(runes)); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
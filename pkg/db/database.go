@@ -5,13 +5,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/plugin/dbresolver"
 	"myapi/config"
-	"myapi/pkg/models" // 新增导入
 )
 
 var gormDB *gorm.DB
@@ -21,14 +22,14 @@ func InitTiDB(cfg *config.GlobalConfig) error {
 	var err error
 	tidbOnce.Do(func() {
 		//zap.S().Infof(cfg.DSN())
-		gormDB, err = gorm.Open(mysql.New(mysql.Config{
-			DSN: cfg.DBConfig.DSN(),
-		}), &gorm.Config{
+		gormDB, err = gorm.Open(newDialector(cfg.DBConfig), &gorm.Config{
 			NowFunc: func() time.Time {
 				ti, _ := time.LoadLocation("Asia/Shanghai")
 				return time.Now().In(ti)
 			},
 			Logger: logger.Default.LogMode(logger.Silent),
+			// 将各数据库违反唯一约束等错误统一转换为gorm的错误
+			TranslateError: true,
 		})
 
 		if err != nil {
@@ -39,7 +40,12 @@ func InitTiDB(cfg *config.GlobalConfig) error {
 			return
 		}
 
-		if err = initTiDB(cfg.DBConfig.MaxConnections); err != nil {
+		// sqlite同时只允许一个写入，:memory:的每个连接都是独立的数据库，只使用一个连接
+		maxConnections := cfg.DBConfig.MaxConnections
+		if cfg.DBConfig.Driver == config.DBDriverSQLite {
+			maxConnections = 1
+		}
+		if err = initTiDB(maxConnections); err != nil {
 			return
 		}
 		zap.S().Debug("database init finished...")
@@ -47,21 +53,36 @@ func InitTiDB(cfg *config.GlobalConfig) error {
 	return err
 }
 
+// newDialector 按配置的驱动创建gorm方言，tidb使用mysql驱动
+func newDialector(cfg *config.DBConfig) gorm.Dialector {
+	switch cfg.Driver {
+	case config.DBDriverPostgres:
+		return postgres.Open(cfg.DSN())
+	case config.DBDriverSQLite:
+		return sqlite.Open(cfg.DSN())
+	default:
+		return mysql.New(mysql.Config{
+			DSN: cfg.DSN(),
+		})
+	}
+}
+
 func initTiDB(maxConnections int) error {
 	maxValue := 100
 	if maxConnections > 0 {
 		maxValue = maxConnections
 	}
 
-	err := gormDB.Use(
+	// 表结构由 migrations 目录下的版本化脚本维护，见 MigrateUp
+	return gormDB.Use(
 		dbresolver.Register(dbresolver.Config{}).
 			SetMaxOpenConns(maxValue),
 	)
-	if err != nil {
-		return err
-	}
-	// 添加模型表的自动迁移
-	return gormDB.AutoMigrate(&models.Model{})
+}
+
+// Dialect 返回当前连接的数据库方言：mysql、postgres或sqlite，tidb为mysql
+func Dialect() string {
+	return gormDB.Dialector.Name()
 }
 
 func GetDB() *gorm.DB {
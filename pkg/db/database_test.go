@@ -0,0 +1,100 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapi/config"
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+	"myapi/pkg/server"
+)
+
+// TestSQLiteSmoke 使用sqlite内存数据库执行全部迁移，并通过接口创建和查询模型
+func TestSQLiteSmoke(t *testing.T) {
+	cfg := config.NewDefaultGlobalConfig()
+	cfg.DBConfig.Driver = config.DBDriverSQLite
+	cfg.DBConfig.Database = ":memory:"
+	if errs := cfg.DBConfig.Validate(); len(errs) > 0 {
+		t.Fatalf("sqlite配置校验失败: %v", errs)
+	}
+	if err := db.InitTiDB(cfg); err != nil {
+		t.Fatalf("连接sqlite失败: %v", err)
+	}
+	if dialect := db.Dialect(); dialect != "sqlite" {
+		t.Fatalf("数据库方言为%s，期望sqlite", dialect)
+	}
+
+	ctx := context.Background()
+	migrations, err := db.LoadMigrations()
+	if err != nil {
+		t.Fatalf("读取迁移脚本失败: %v", err)
+	}
+	applied, err := db.MigrateUp(ctx)
+	if err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("执行了%d个迁移，期望%d个", len(applied), len(migrations))
+	}
+	// 全部回滚后重新执行，确认down脚本可用
+	if _, err := db.MigrateDown(ctx, len(migrations)); err != nil {
+		t.Fatalf("回滚迁移失败: %v", err)
+	}
+	if _, err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("重新执行迁移失败: %v", err)
+	}
+	statuses, err := db.GetMigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("查询迁移状态失败: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("迁移%06d_%s没有执行", s.Version, s.Name)
+		}
+	}
+
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := server.NewLocalHandler(auditor)
+	body, _ := json.Marshal(models.CreateModelRequest{
+		Name:     "smoke-chat",
+		Endpoint: "http://127.0.0.1:9/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     models.ModelTypeChat,
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/models/create", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("创建模型返回%d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/models/get?name=smoke", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("查询模型列表返回%d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			List  []models.Model `json:"list"`
+			Total int64          `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析模型列表失败: %v", err)
+	}
+	if resp.Data.Total != 1 || len(resp.Data.List) != 1 {
+		t.Fatalf("模型列表为%+v，期望只有刚创建的模型", resp.Data)
+	}
+	if m := resp.Data.List[0]; m.Name != "smoke-chat" || !m.Enabled || m.Version != 1 {
+		t.Errorf("查询到的模型为%+v，期望启用且版本号为1", m)
+	}
+}
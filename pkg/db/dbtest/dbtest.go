@@ -0,0 +1,49 @@
+// Package dbtest 为需要数据库的测试提供连接
+package dbtest
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"myapi/config"
+	"myapi/pkg/db"
+)
+
+// ConfigEnv 测试使用的配置文件路径的环境变量，需要使用绝对路径；没有设置时使用sqlite内存数据库
+const ConfigEnv = "MYAPI_TEST_CONFIG"
+
+var (
+	setupOnce sync.Once
+	setupErr  error
+)
+
+// Setup 连接测试数据库并执行迁移，同一个测试进程只初始化一次
+func Setup(t testing.TB) {
+	t.Helper()
+	setupOnce.Do(func() {
+		cfg, err := loadConfig()
+		if err != nil {
+			setupErr = err
+			return
+		}
+		if setupErr = db.InitTiDB(cfg); setupErr != nil {
+			return
+		}
+		_, setupErr = db.MigrateUp(context.Background())
+	})
+	if setupErr != nil {
+		t.Fatalf("初始化测试数据库失败: %v", setupErr)
+	}
+}
+
+func loadConfig() (*config.GlobalConfig, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return config.TryLoadFromDisk(path)
+	}
+	cfg := config.NewDefaultGlobalConfig()
+	cfg.DBConfig.Driver = config.DBDriverSQLite
+	cfg.DBConfig.Database = ":memory:"
+	return cfg, nil
+}
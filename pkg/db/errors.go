@@ -0,0 +1,20 @@
+package db
+
+import (
+	"errors"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// mysqlDuplicateEntry MySQL违反唯一约束的错误码
+const mysqlDuplicateEntry = 1062
+
+// IsDuplicateKeyError 判断错误是否为违反唯一约束，postgres和sqlite的错误由gorm转换为ErrDuplicatedKey
+func IsDuplicateKeyError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDuplicateEntry
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockTimeout 等待数据库锁的秒数
+const lockTimeout = 60
+
+// Lock 多个副本之间互斥的数据库锁：MySQL使用GET_LOCK按名称加锁，PostgreSQL使用advisory lock的整数键；sqlite为单进程使用，不加锁
+type Lock struct {
+	Name string
+	Key  int64
+}
+
+var (
+	// migrationLock 迁移时持有的锁，避免多个副本同时执行DDL
+	migrationLock = Lock{Name: "myapi_schema_migration", Key: 7311022805}
+	// ModelSyncLock 同步模型文件时持有的锁，避免多个副本同时按文件写入t_model
+	ModelSyncLock = Lock{Name: "myapi_model_sync", Key: 7311022806}
+)
+
+// WithLock 持有锁执行fn，锁占用单独的连接，fn中可以正常使用GetDB
+func WithLock(ctx context.Context, lock Lock, fn func() error) error {
+	if Dialect() == "sqlite" {
+		return fn()
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "获取数据库连接失败")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	unlock, err := acquireLock(ctx, conn, lock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
+
+// acquireLock 在conn上获取锁，返回释放锁的函数
+func acquireLock(ctx context.Context, conn *sql.Conn, lock Lock) (func(), error) {
+	switch Dialect() {
+	case "mysql":
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lock.Name, lockTimeout).Scan(&locked); err != nil {
+			return nil, errors.Wrapf(err, "获取数据库锁%s失败", lock.Name)
+		}
+		if locked.Int64 != 1 {
+			return nil, errors.Errorf("等待数据库锁%s超时，可能有其他实例正在持有", lock.Name)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lock.Name)
+		}, nil
+	case "postgres":
+		lockCtx, cancel := context.WithTimeout(ctx, lockTimeout*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", lock.Key); err != nil {
+			return nil, errors.Wrapf(err, "获取数据库锁%s失败，可能有其他实例正在持有", lock.Name)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lock.Key)
+		}, nil
+	default:
+		return func() {}, nil
+	}
+}
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+//go:embed migrations
+var migrationFS embed.FS
+
+// schemaMigrationTimeTypes 各数据库中applied_at字段的类型
+var schemaMigrationTimeTypes = map[string]string{
+	"mysql":    "datetime(3)",
+	"postgres": "timestamptz",
+	"sqlite":   "datetime",
+}
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移脚本，Down为空表示不能回滚
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// LoadMigrations 读取内嵌的迁移脚本，按版本号升序
+func LoadMigrations() ([]Migration, error) {
+	migrationDir := path.Join("migrations", Dialect())
+	entries, err := fs.ReadDir(migrationFS, migrationDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "读取%s的迁移脚本失败", Dialect())
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.Errorf("迁移脚本名称不合法: %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := migrationFS.ReadFile(path.Join(migrationDir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "读取迁移脚本%s失败", entry.Name())
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, errors.Errorf("迁移版本%d的脚本名称不一致: %s, %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("迁移版本%d缺少up脚本", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp 按顺序执行所有未执行的迁移，返回本次执行的迁移
+func MigrateUp(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := execScript(ctx, conn, m.Up); err != nil {
+				return errors.Wrapf(err, "执行迁移%d_%s失败，DDL可能已部分生效，需要手动检查", m.Version, m.Name)
+			}
+			if _, err := conn.ExecContext(ctx, rebind("INSERT INTO t_schema_migration (version, name, applied_at) VALUES (?, ?, ?)"),
+				m.Version, m.Name, time.Now()); err != nil {
+				return errors.Wrapf(err, "记录迁移%d_%s失败", m.Version, m.Name)
+			}
+			zap.S().Infof("已执行数据库迁移: %06d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown 按版本倒序回滚最近执行的steps个迁移，返回本次回滚的迁移
+func MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return errors.Errorf("迁移%d_%s没有down脚本，不能回滚", m.Version, m.Name)
+			}
+			if err := execScript(ctx, conn, m.Down); err != nil {
+				return errors.Wrapf(err, "回滚迁移%d_%s失败，DDL可能已部分生效，需要手动检查", m.Version, m.Name)
+			}
+			if _, err := conn.ExecContext(ctx, rebind("DELETE FROM t_schema_migration WHERE version = ?"), m.Version); err != nil {
+				return errors.Wrapf(err, "删除迁移记录%d_%s失败", m.Version, m.Name)
+			}
+			zap.S().Infof("已回滚数据库迁移: %06d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// GetMigrationStatus 返回所有迁移的执行状态，包括数据库中存在但当前版本没有的迁移
+func GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			status := MigrationStatus{Version: m.Version, Name: m.Name}
+			if s, ok := applied[m.Version]; ok {
+				status.AppliedAt = s.AppliedAt
+				delete(applied, m.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, s := range applied {
+			statuses = append(statuses, s)
+		}
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].Version < statuses[j].Version
+		})
+		return nil
+	})
+	return statuses, err
+}
+
+// withMigrationLock 在持有迁移锁的同一连接上执行fn
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "获取数据库连接失败")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	unlock, err := acquireLock(ctx, conn, migrationLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	createTable := "CREATE TABLE IF NOT EXISTS t_schema_migration (version bigint NOT NULL, name varchar(255) NOT NULL, " +
+		"applied_at " + schemaMigrationTimeTypes[Dialect()] + " NOT NULL, PRIMARY KEY (version))"
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return errors.Wrap(err, "创建迁移版本表失败")
+	}
+	return fn(conn)
+}
+
+// rebind 将?占位符转换为当前数据库的格式
+func rebind(query string) string {
+	if Dialect() != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// appliedMigrations 查询已执行的迁移
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM t_schema_migration")
+	if err != nil {
+		return nil, errors.Wrap(err, "查询迁移版本失败")
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var s MigrationStatus
+		var appliedAt time.Time
+		if err := rows.Scan(&s.Version, &s.Name, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "查询迁移版本失败")
+		}
+		s.AppliedAt = &appliedAt
+		applied[s.Version] = s
+	}
+	return applied, rows.Err()
+}
+
+// execScript 逐条执行脚本中的语句，语句以行尾的分号结束，忽略--开头的注释行
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(line, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,14 @@
+-- 初始表结构，与引入迁移之前 AutoMigrate 创建的 t_model 一致，已有数据库中的表保持不变
+CREATE TABLE IF NOT EXISTS `t_model` (
+  `model_id` varchar(64),
+  `name` varchar(255) NOT NULL,
+  `endpoint` varchar(255) NOT NULL,
+  `api_key` varchar(255) NOT NULL,
+  `timeout` bigint NOT NULL,
+  `type` varchar(255) NOT NULL,
+  `dimensions` bigint NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`model_id`),
+  UNIQUE INDEX `idx_t_model_name` (`name`)
+);
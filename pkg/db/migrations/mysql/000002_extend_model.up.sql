@@ -0,0 +1,17 @@
+-- 模型的上下文窗口、分词、能力标记、停用与软删除以及乐观锁版本号
+-- 每条语句只做一项变更，TiDB 6.2 之前不支持在一条 ALTER TABLE 中做多项变更
+ALTER TABLE `t_model` ALTER COLUMN `dimensions` SET DEFAULT 0;
+ALTER TABLE `t_model` ADD COLUMN `context_window` bigint NOT NULL DEFAULT 0;
+ALTER TABLE `t_model` ADD COLUMN `max_output_tokens` bigint NOT NULL DEFAULT 0;
+ALTER TABLE `t_model` ADD COLUMN `context_strategy` varchar(32) NOT NULL DEFAULT '';
+ALTER TABLE `t_model` ADD COLUMN `summarizer_model_id` varchar(64);
+ALTER TABLE `t_model` ADD COLUMN `summarizer_model` varchar(255);
+ALTER TABLE `t_model` ADD COLUMN `tokenizer` varchar(32);
+ALTER TABLE `t_model` ADD COLUMN `supports_vision` boolean NOT NULL DEFAULT false;
+ALTER TABLE `t_model` ADD COLUMN `supports_tools` boolean NOT NULL DEFAULT false;
+ALTER TABLE `t_model` ADD COLUMN `supports_json_mode` boolean NOT NULL DEFAULT false;
+ALTER TABLE `t_model` ADD COLUMN `supports_streaming` boolean NOT NULL DEFAULT false;
+ALTER TABLE `t_model` ADD COLUMN `enabled` boolean NOT NULL DEFAULT true;
+ALTER TABLE `t_model` ADD COLUMN `deleted_at` datetime(3) NULL;
+ALTER TABLE `t_model` ADD COLUMN `version` bigint NOT NULL DEFAULT 1;
+ALTER TABLE `t_model` ADD INDEX `idx_t_model_deleted_at` (`deleted_at`);
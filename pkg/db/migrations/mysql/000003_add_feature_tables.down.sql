@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS `t_audit_log`;
+DROP TABLE IF EXISTS `t_kb_document`;
+DROP TABLE IF EXISTS `t_knowledge_base`;
+DROP TABLE IF EXISTS `t_image_caption`;
+DROP TABLE IF EXISTS `t_prompt_template`;
+DROP TABLE IF EXISTS `t_message`;
+DROP TABLE IF EXISTS `t_conversation`;
+DROP TABLE IF EXISTS `t_model_revision`;
+DROP TABLE IF EXISTS `t_model_status`;
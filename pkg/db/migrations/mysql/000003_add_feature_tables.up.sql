@@ -0,0 +1,127 @@
+-- 对话、提示词模板、图片描述、知识库、模型状态、修订记录和审计日志
+CREATE TABLE `t_model_status` (
+  `model_id` varchar(64),
+  `status` varchar(16) NOT NULL,
+  `last_check_at` datetime(3) NULL,
+  `last_error` text,
+  `latency_ms` bigint,
+  `consecutive_failures` bigint,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`model_id`)
+);
+
+CREATE TABLE `t_model_revision` (
+  `revision_id` varchar(64),
+  `model_id` varchar(64) NOT NULL,
+  `revision` bigint NOT NULL,
+  `action` varchar(32) NOT NULL,
+  `actor` varchar(255),
+  `snapshot` json,
+  `diff` json,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`revision_id`),
+  UNIQUE INDEX `idx_model_revision` (`model_id`, `revision`)
+);
+
+CREATE TABLE `t_conversation` (
+  `conversation_id` varchar(64),
+  `title` varchar(255),
+  `model_id` varchar(64) NOT NULL,
+  `model` varchar(255) NOT NULL,
+  `system_prompt` text,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`conversation_id`),
+  INDEX `idx_t_conversation_model_id` (`model_id`)
+);
+
+CREATE TABLE `t_message` (
+  `message_id` varchar(64),
+  `conversation_id` varchar(64) NOT NULL,
+  `role` varchar(32) NOT NULL,
+  `content` longtext NOT NULL,
+  `prompt_tokens` bigint,
+  `completion_tokens` bigint,
+  `total_tokens` bigint,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`message_id`),
+  INDEX `idx_t_message_conversation_id` (`conversation_id`)
+);
+
+CREATE TABLE `t_prompt_template` (
+  `template_id` varchar(64),
+  `name` varchar(255) NOT NULL,
+  `version` bigint NOT NULL,
+  `active` boolean NOT NULL DEFAULT false,
+  `description` varchar(1024),
+  `body` text NOT NULL,
+  `variables` text,
+  `model_id` varchar(64),
+  `model` varchar(255),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`template_id`),
+  UNIQUE INDEX `idx_prompt_name_version` (`name`, `version`)
+);
+
+CREATE TABLE `t_image_caption` (
+  `image_id` varchar(255),
+  `task_id` varchar(255) NOT NULL,
+  `url` text NOT NULL,
+  `caption` text NOT NULL,
+  `model_id` varchar(64) NOT NULL,
+  `embedded` boolean NOT NULL DEFAULT false,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`image_id`),
+  INDEX `idx_t_image_caption_task_id` (`task_id`)
+);
+
+CREATE TABLE `t_knowledge_base` (
+  `kb_id` varchar(64),
+  `name` varchar(255) NOT NULL,
+  `description` varchar(1024),
+  `embedding_model_id` varchar(64) NOT NULL,
+  `embedding_model` varchar(255),
+  `collection` varchar(255) NOT NULL,
+  `chunk_size` bigint NOT NULL,
+  `chunk_overlap` bigint NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`kb_id`),
+  UNIQUE INDEX `idx_t_knowledge_base_name` (`name`),
+  UNIQUE INDEX `idx_t_knowledge_base_collection` (`collection`)
+);
+
+CREATE TABLE `t_kb_document` (
+  `document_id` varchar(64),
+  `kb_id` varchar(64) NOT NULL,
+  `title` varchar(1024),
+  `format` varchar(32) NOT NULL,
+  `chunk_count` bigint,
+  `char_count` bigint,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`document_id`),
+  INDEX `idx_t_kb_document_kb_id` (`kb_id`)
+);
+
+CREATE TABLE `t_audit_log` (
+  `audit_id` varchar(64),
+  `request_id` varchar(64),
+  `actor` varchar(255),
+  `client_ip` varchar(64),
+  `action` varchar(64),
+  `method` varchar(16),
+  `route` varchar(255),
+  `path` varchar(1024),
+  `model_id` varchar(64),
+  `status` bigint,
+  `latency_ms` bigint,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`audit_id`),
+  INDEX `idx_t_audit_log_request_id` (`request_id`),
+  INDEX `idx_t_audit_log_actor` (`actor`),
+  INDEX `idx_t_audit_log_action` (`action`),
+  INDEX `idx_t_audit_log_model_id` (`model_id`),
+  INDEX `idx_t_audit_log_created_at` (`created_at`)
+);
@@ -0,0 +1,18 @@
+-- vectorStore.backend 为 mysql 时使用的集合和向量记录
+CREATE TABLE `t_vector_collection` (
+  `name` varchar(255),
+  `dimensions` bigint NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`name`)
+);
+
+CREATE TABLE `t_vector_record` (
+  `collection` varchar(255),
+  `record_id` varchar(512),
+  `content` longtext,
+  `metadata` json,
+  `vector` mediumblob NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`collection`, `record_id`)
+);
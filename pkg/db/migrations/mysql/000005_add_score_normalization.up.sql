@@ -0,0 +1,2 @@
+-- 重排序模型的分数归一化方式
+ALTER TABLE `t_model` ADD COLUMN `score_normalization` varchar(32) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- 初始表结构，与 mysql 的 000001_init 一致
+CREATE TABLE IF NOT EXISTS t_model (
+  model_id varchar(64),
+  name varchar(255) NOT NULL,
+  endpoint varchar(255) NOT NULL,
+  api_key varchar(255) NOT NULL,
+  timeout bigint NOT NULL,
+  type varchar(255) NOT NULL,
+  dimensions bigint NOT NULL,
+  created_at timestamptz,
+  updated_at timestamptz,
+  PRIMARY KEY (model_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_t_model_name ON t_model (name);
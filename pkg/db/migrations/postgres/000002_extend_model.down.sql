@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_t_model_deleted_at;
+ALTER TABLE t_model
+  DROP COLUMN version,
+  DROP COLUMN deleted_at,
+  DROP COLUMN enabled,
+  DROP COLUMN supports_streaming,
+  DROP COLUMN supports_json_mode,
+  DROP COLUMN supports_tools,
+  DROP COLUMN supports_vision,
+  DROP COLUMN tokenizer,
+  DROP COLUMN summarizer_model,
+  DROP COLUMN summarizer_model_id,
+  DROP COLUMN context_strategy,
+  DROP COLUMN max_output_tokens,
+  DROP COLUMN context_window,
+  ALTER COLUMN dimensions DROP DEFAULT;
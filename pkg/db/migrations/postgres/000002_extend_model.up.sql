@@ -0,0 +1,17 @@
+-- 模型的上下文窗口、分词、能力标记、停用与软删除以及乐观锁版本号
+ALTER TABLE t_model
+  ALTER COLUMN dimensions SET DEFAULT 0,
+  ADD COLUMN context_window bigint NOT NULL DEFAULT 0,
+  ADD COLUMN max_output_tokens bigint NOT NULL DEFAULT 0,
+  ADD COLUMN context_strategy varchar(32) NOT NULL DEFAULT '',
+  ADD COLUMN summarizer_model_id varchar(64),
+  ADD COLUMN summarizer_model varchar(255),
+  ADD COLUMN tokenizer varchar(32),
+  ADD COLUMN supports_vision boolean NOT NULL DEFAULT false,
+  ADD COLUMN supports_tools boolean NOT NULL DEFAULT false,
+  ADD COLUMN supports_json_mode boolean NOT NULL DEFAULT false,
+  ADD COLUMN supports_streaming boolean NOT NULL DEFAULT false,
+  ADD COLUMN enabled boolean NOT NULL DEFAULT true,
+  ADD COLUMN deleted_at timestamptz,
+  ADD COLUMN version bigint NOT NULL DEFAULT 1;
+CREATE INDEX idx_t_model_deleted_at ON t_model (deleted_at);
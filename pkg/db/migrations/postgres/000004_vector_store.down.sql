@@ -0,0 +1 @@
+-- 000004_vector_store 在 postgres 中没有创建表
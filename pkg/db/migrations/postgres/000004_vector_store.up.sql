@@ -0,0 +1 @@
+-- 数据库向量存储不支持 postgres，此版本只占位，保持各数据库的迁移版本一致
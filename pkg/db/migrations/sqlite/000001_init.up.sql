@@ -0,0 +1,14 @@
+-- 初始表结构，与 mysql 的 000001_init 一致；sqlite 不能修改字段的默认值，dimensions 直接带默认值
+CREATE TABLE IF NOT EXISTS t_model (
+  model_id varchar(64),
+  name varchar(255) NOT NULL,
+  endpoint varchar(255) NOT NULL,
+  api_key varchar(255) NOT NULL,
+  timeout integer NOT NULL,
+  type varchar(255) NOT NULL,
+  dimensions integer NOT NULL DEFAULT 0,
+  created_at datetime,
+  updated_at datetime,
+  PRIMARY KEY (model_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_t_model_name ON t_model (name);
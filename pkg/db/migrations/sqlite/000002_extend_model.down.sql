@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_t_model_deleted_at;
+ALTER TABLE t_model DROP COLUMN version;
+ALTER TABLE t_model DROP COLUMN deleted_at;
+ALTER TABLE t_model DROP COLUMN enabled;
+ALTER TABLE t_model DROP COLUMN supports_streaming;
+ALTER TABLE t_model DROP COLUMN supports_json_mode;
+ALTER TABLE t_model DROP COLUMN supports_tools;
+ALTER TABLE t_model DROP COLUMN supports_vision;
+ALTER TABLE t_model DROP COLUMN tokenizer;
+ALTER TABLE t_model DROP COLUMN summarizer_model;
+ALTER TABLE t_model DROP COLUMN summarizer_model_id;
+ALTER TABLE t_model DROP COLUMN context_strategy;
+ALTER TABLE t_model DROP COLUMN max_output_tokens;
+ALTER TABLE t_model DROP COLUMN context_window;
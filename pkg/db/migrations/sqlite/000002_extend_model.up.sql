@@ -0,0 +1,15 @@
+-- 模型的上下文窗口、分词、能力标记、停用与软删除以及乐观锁版本号
+ALTER TABLE t_model ADD COLUMN context_window integer NOT NULL DEFAULT 0;
+ALTER TABLE t_model ADD COLUMN max_output_tokens integer NOT NULL DEFAULT 0;
+ALTER TABLE t_model ADD COLUMN context_strategy varchar(32) NOT NULL DEFAULT '';
+ALTER TABLE t_model ADD COLUMN summarizer_model_id varchar(64);
+ALTER TABLE t_model ADD COLUMN summarizer_model varchar(255);
+ALTER TABLE t_model ADD COLUMN tokenizer varchar(32);
+ALTER TABLE t_model ADD COLUMN supports_vision numeric NOT NULL DEFAULT false;
+ALTER TABLE t_model ADD COLUMN supports_tools numeric NOT NULL DEFAULT false;
+ALTER TABLE t_model ADD COLUMN supports_json_mode numeric NOT NULL DEFAULT false;
+ALTER TABLE t_model ADD COLUMN supports_streaming numeric NOT NULL DEFAULT false;
+ALTER TABLE t_model ADD COLUMN enabled numeric NOT NULL DEFAULT true;
+ALTER TABLE t_model ADD COLUMN deleted_at datetime;
+ALTER TABLE t_model ADD COLUMN version integer NOT NULL DEFAULT 1;
+CREATE INDEX idx_t_model_deleted_at ON t_model (deleted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_vector_record;
+DROP TABLE IF EXISTS t_vector_collection;
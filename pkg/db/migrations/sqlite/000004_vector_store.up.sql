@@ -0,0 +1,18 @@
+-- vectorStore.backend 为 mysql 时使用的集合和向量记录
+CREATE TABLE t_vector_collection (
+  name varchar(255),
+  dimensions integer NOT NULL,
+  created_at datetime,
+  PRIMARY KEY (name)
+);
+
+CREATE TABLE t_vector_record (
+  collection varchar(255),
+  record_id varchar(512),
+  content text,
+  metadata text,
+  vector blob NOT NULL,
+  created_at datetime,
+  updated_at datetime,
+  PRIMARY KEY (collection, record_id)
+);
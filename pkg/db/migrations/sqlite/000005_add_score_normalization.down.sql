@@ -0,0 +1 @@
+ALTER TABLE t_model DROP COLUMN score_normalization;
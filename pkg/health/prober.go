@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"myapi/config"
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// latencyWeight 滑动平均延迟中最新一次探测的权重
+const latencyWeight = 0.3
+
+// Prober 定期探测模型并记录健康状态，连续失败的模型被标记为down后不再转发请求
+type Prober struct {
+	cfg *config.HealthConfig
+}
+
+// NewProber 创建健康检查器
+func NewProber(cfg *config.HealthConfig) *Prober {
+	return &Prober{cfg: cfg}
+}
+
+// Run 启动时恢复已记录的down状态，之后按间隔探测全部启用的模型，直到ctx结束
+func (p *Prober) Run(ctx context.Context) error {
+	if err := p.restore(ctx); err != nil {
+		zap.S().Warnf("恢复模型健康状态失败: %v", err)
+	}
+	ticker := time.NewTicker(time.Duration(p.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	zap.S().Infof("模型健康检查已启动，间隔%ds", p.cfg.Interval)
+	for {
+		p.probeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// restore 从t_model_status加载down状态，避免重启后在首轮探测前转发给不可用的模型
+func (p *Prober) restore(ctx context.Context) error {
+	var statuses []models.ModelStatus
+	if err := db.GetDBWithContext(ctx).Where("status = ?", models.ModelStatusDown).Find(&statuses).Error; err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		llm.SetModelDown(s.ModelID, true)
+	}
+	return nil
+}
+
+// probeAll 并发探测全部启用的模型，不支持探测的模型类型跳过
+func (p *Prober) probeAll(ctx context.Context) {
+	var list []models.Model
+	if err := db.GetDBWithContext(ctx).Where("enabled = ?", true).Find(&list).Error; err != nil {
+		zap.S().Errorf("健康检查查询模型列表失败: %v", err)
+		return
+	}
+	sem := make(chan struct{}, p.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range list {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(model *models.Model) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := p.probe(ctx, model); err != nil {
+				zap.S().Errorf("模型%s健康检查失败: %v", model.Name, err)
+			}
+		}(&list[i])
+	}
+	wg.Wait()
+}
+
+// probe 探测单个模型并保存状态
+func (p *Prober) probe(ctx context.Context, model *models.Model) error {
+	probeCtx, cancel := context.WithTimeout(ctx, time.Duration(p.cfg.Timeout)*time.Second)
+	defer cancel()
+	result, err := llm.Probe(probeCtx, model, "")
+	if err != nil {
+		if errors.Is(err, llm.ErrUnsupportedRequest) {
+			return nil
+		}
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	database := db.GetDBWithContext(ctx)
+	var status models.ModelStatus
+	if err := database.Where("model_id = ?", model.ModelID).First(&status).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Wrap(err, "查询模型状态失败")
+		}
+		status = models.ModelStatus{ModelID: model.ModelID}
+	}
+	previous := status.Status
+	p.update(&status, result)
+	if err := database.Clauses(clause.OnConflict{UpdateAll: true}).Create(&status).Error; err != nil {
+		return errors.Wrap(err, "保存模型状态失败")
+	}
+	llm.SetModelDown(model.ModelID, status.Status == models.ModelStatusDown)
+	if previous != status.Status {
+		zap.S().Infof("模型%s健康状态变为%s: %s", model.Name, status.Status, status.LastError)
+	}
+	return nil
+}
+
+// update 按探测结果更新状态：端点不可达、鉴权失败、限流或上游5xx计为失败，连续失败达到阈值时为down；
+// 探测成功但平均延迟超过阈值或响应不符合预期（如向量维度不一致）时为degraded
+func (p *Prober) update(status *models.ModelStatus, result *models.ModelTestResult) {
+	now := time.Now()
+	status.LastCheckAt = &now
+	status.LastError = result.Error
+
+	if isFailure(result) {
+		status.ConsecutiveFailures++
+		if status.ConsecutiveFailures >= p.cfg.FailureThreshold {
+			status.Status = models.ModelStatusDown
+		} else {
+			status.Status = models.ModelStatusDegraded
+		}
+		return
+	}
+
+	status.ConsecutiveFailures = 0
+	if status.LatencyMS == 0 {
+		status.LatencyMS = result.LatencyMS
+	} else {
+		status.LatencyMS = int64(latencyWeight*float64(result.LatencyMS) + (1-latencyWeight)*float64(status.LatencyMS))
+	}
+	status.Status = models.ModelStatusHealthy
+	if !result.Success || status.LatencyMS > int64(p.cfg.DegradedLatency) {
+		status.Status = models.ModelStatusDegraded
+	}
+}
+
+// isFailure 判断探测是否失败；注册表没有上游模型名，按模型名称探测可能被上游以其他4xx拒绝，
+// 这说明端点可达且鉴权通过，不计为失败
+func isFailure(result *models.ModelTestResult) bool {
+	return !result.Reachable || !result.Authorized ||
+		result.StatusCode == http.StatusTooManyRequests || result.StatusCode >= http.StatusInternalServerError
+}
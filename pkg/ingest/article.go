@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+
+	"myapi/config"
+	"myapi/pkg/chunker"
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ArticleIngester 将文章切分、向量化后写入Milvus
+type ArticleIngester struct {
+	cfg   *config.ArticleConfig
+	store *ChunkStore
+}
+
+// NewArticleIngester 创建文章入库处理器
+func NewArticleIngester(cfg *config.ArticleConfig, store *ChunkStore) *ArticleIngester {
+	return &ArticleIngester{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// Ingest 处理一篇文章，返回写入的分块数；progress 在每批向量化完成后调用，可以为nil
+func (i *ArticleIngester) Ingest(ctx context.Context, article *models.Article, progress func()) (int, error) {
+	if strings.TrimSpace(article.ArticleID) == "" || strings.TrimSpace(article.Content) == "" {
+		return 0, errors.Wrap(ErrInvalidInput, "文章ID和内容不能为空")
+	}
+	model, err := LoadEmbeddingModel(ctx, i.cfg.EmbeddingModelID)
+	if err != nil {
+		return 0, err
+	}
+
+	chunks := chunker.Split(article.Content, i.cfg.ChunkSize, i.cfg.ChunkOverlap)
+	vectors, err := EmbedBatches(ctx, model, i.cfg.EmbeddingModel, chunks, i.cfg.BatchSize, progress)
+	if err != nil {
+		return 0, err
+	}
+	if err := i.store.Replace(ctx, article.ArticleID, article.Title, chunks, vectors, model.Dimensions); err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// LoadEmbeddingModel 加载并校验嵌入模型
+func LoadEmbeddingModel(ctx context.Context, modelID string) (*models.Model, error) {
+	var model models.Model
+	if err := db.GetDBWithContext(ctx).Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(ErrModelNotFound, "模型ID: %s", modelID)
+		}
+		return nil, errors.Wrap(err, "查询嵌入模型失败")
+	}
+	if !model.IsType(models.ModelTypeEmbedding) {
+		return nil, errors.Wrapf(ErrNotEmbeddingModel, "模型%s的类型为%s", model.Name, model.Type)
+	}
+	if model.Dimensions <= 0 {
+		return nil, errors.Wrapf(ErrDimensionMismatch, "模型%s没有配置向量维度", model.Name)
+	}
+	return &model, nil
+}
+
+// EmbedBatches 分批向量化文本并校验向量维度与模型配置一致
+func EmbedBatches(ctx context.Context, model *models.Model, upstreamModel string, texts []string, batchSize int, progress func()) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		batch, err := llm.Embed(ctx, model, upstreamModel, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range batch {
+			if len(v) != model.Dimensions {
+				return nil, errors.Wrapf(ErrDimensionMismatch, "模型%s返回的向量维度为%d，配置的维度为%d", model.Name, len(v), model.Dimensions)
+			}
+		}
+		vectors = append(vectors, batch...)
+		if progress != nil {
+			progress()
+		}
+	}
+	return vectors, nil
+}
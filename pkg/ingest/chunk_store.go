@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"context"
+	"strconv"
+
+	"myapi/pkg/vectorstore"
+)
+
+// 分块记录的元数据字段，doc_id 为分块所属的文档（文章、图片等）
+const (
+	metaDocID      = "doc_id"
+	metaChunkIndex = "chunk_index"
+	metaTitle      = "title"
+)
+
+// ChunkStore 文档分块及其向量在一个向量集合中的存储
+type ChunkStore struct {
+	store      vectorstore.Store
+	collection string
+}
+
+// ChunkHit 相似度检索命中的分块，Score为余弦相似度
+type ChunkHit struct {
+	ID         string
+	DocID      string
+	ChunkIndex int
+	Title      string
+	Content    string
+	Score      float32
+}
+
+// NewChunkStore 创建分块存储
+func NewChunkStore(store vectorstore.Store, collection string) *ChunkStore {
+	return &ChunkStore{
+		store:      store,
+		collection: collection,
+	}
+}
+
+// Replace 用新的分块替换文档原有的全部分块，重复处理同一文档结果一致；集合不存在时按维度创建
+func (s *ChunkStore) Replace(ctx context.Context, docID, title string, chunks []string, vectors [][]float32, dim int) error {
+	if err := s.store.CreateCollection(ctx, s.collection, dim); err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, docID); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	records := make([]vectorstore.Record, len(chunks))
+	for i := range chunks {
+		records[i] = vectorstore.Record{
+			ID:      docID + "#" + strconv.Itoa(i),
+			Content: chunks[i],
+			Metadata: map[string]any{
+				metaDocID:      docID,
+				metaChunkIndex: i,
+				metaTitle:      title,
+			},
+			Vector: vectors[i],
+		}
+	}
+	return s.store.Upsert(ctx, s.collection, records)
+}
+
+// Delete 删除文档的全部分块
+func (s *ChunkStore) Delete(ctx context.Context, docID string) error {
+	return s.store.Delete(ctx, s.collection, vectorstore.Filter{metaDocID: docID})
+}
+
+// Search 检索与向量最相似的topK个分块，按相似度降序返回，集合不存在时返回空结果
+func (s *ChunkStore) Search(ctx context.Context, vector []float32, topK int) ([]ChunkHit, error) {
+	results, err := s.store.Search(ctx, s.collection, vector, topK, nil)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]ChunkHit, 0, len(results))
+	for _, r := range results {
+		hit := ChunkHit{ID: r.ID, Content: r.Content, Score: r.Score}
+		hit.DocID, _ = r.Metadata[metaDocID].(string)
+		hit.Title, _ = r.Metadata[metaTitle].(string)
+		switch index := r.Metadata[metaChunkIndex].(type) {
+		case int:
+			hit.ChunkIndex = index
+		case float64:
+			hit.ChunkIndex = int(index)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// Count 统计集合中的分块数
+func (s *ChunkStore) Count(ctx context.Context) (int64, error) {
+	return s.store.Count(ctx, s.collection, nil)
+}
+
+// Drop 删除整个集合
+func (s *ChunkStore) Drop(ctx context.Context) error {
+	return s.store.DropCollection(ctx, s.collection)
+}
@@ -0,0 +1,24 @@
+package ingest
+
+import (
+	"myapi/pkg/vectorstore"
+
+	"github.com/pkg/errors"
+)
+
+// 以下错误无法通过重试恢复
+var (
+	ErrInvalidInput      = errors.New("输入内容不合法")
+	ErrModelNotFound     = errors.New("模型不存在")
+	ErrNotEmbeddingModel = errors.New("模型不是嵌入模型")
+	ErrDimensionMismatch = vectorstore.ErrDimensionMismatch
+)
+
+// IsPermanent 判断错误是否无法通过重试恢复
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrInvalidInput) ||
+		errors.Is(err, ErrModelNotFound) ||
+		errors.Is(err, ErrNotEmbeddingModel) ||
+		errors.Is(err, ErrDimensionMismatch) ||
+		errors.Is(err, vectorstore.ErrInvalidFilter)
+}
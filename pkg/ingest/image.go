@@ -0,0 +1,171 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+
+	"myapi/config"
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImageCaptioner 使用多模态模型描述图片，保存描述文本并可选地写入向量
+type ImageCaptioner struct {
+	cfg   *config.ImagesConfig
+	store *ChunkStore
+}
+
+// NewImageCaptioner 创建图片描述处理器，store为nil时只保存描述文本
+func NewImageCaptioner(cfg *config.ImagesConfig, store *ChunkStore) *ImageCaptioner {
+	return &ImageCaptioner{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// Process 依次处理通知中的图片，已成功处理过的图片直接复用结果。
+// 单张图片的永久错误记录在结果中；存在可重试的错误时返回该错误，调用方应稍后重试
+func (p *ImageCaptioner) Process(ctx context.Context, n *models.ImageProcNotification, progress func()) (*models.ImageProcResult, error) {
+	visionModel, err := p.loadVisionModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var embeddingModel *models.Model
+	if p.store != nil && p.cfg.EmbeddingModelID != "" {
+		if embeddingModel, err = LoadEmbeddingModel(ctx, p.cfg.EmbeddingModelID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.ImageProcResult{TaskID: n.TaskID}
+	var retryErr error
+	for _, image := range n.Images {
+		caption, err := p.processImage(ctx, n.TaskID, image, visionModel, embeddingModel)
+		item := models.ImageProcItem{ImageID: image.ImageID, Status: models.ImageProcSuccess, Caption: caption}
+		if err != nil {
+			item.Status = models.ImageProcFailed
+			item.Error = err.Error()
+			if !IsPermanent(err) && !llm.IsPermanentError(err) {
+				retryErr = err
+			}
+		}
+		result.Images = append(result.Images, item)
+		if progress != nil {
+			progress()
+		}
+	}
+	result.Status = summarizeStatus(result.Images)
+	return result, retryErr
+}
+
+// Result 根据已保存的描述生成处理结果，未处理成功的图片标记为失败
+func (p *ImageCaptioner) Result(ctx context.Context, n *models.ImageProcNotification, cause error) *models.ImageProcResult {
+	result := &models.ImageProcResult{TaskID: n.TaskID}
+	if cause != nil {
+		result.Error = cause.Error()
+	}
+	for _, image := range n.Images {
+		item := models.ImageProcItem{ImageID: image.ImageID, Status: models.ImageProcFailed}
+		if caption, ok := p.existingCaption(ctx, image); ok {
+			item.Status = models.ImageProcSuccess
+			item.Caption = caption.Caption
+		} else if cause != nil {
+			item.Error = cause.Error()
+		}
+		result.Images = append(result.Images, item)
+	}
+	result.Status = summarizeStatus(result.Images)
+	return result
+}
+
+func (p *ImageCaptioner) processImage(ctx context.Context, taskID string, image models.ImageRef, visionModel, embeddingModel *models.Model) (string, error) {
+	if caption, ok := p.existingCaption(ctx, image); ok && (embeddingModel == nil || caption.Embedded) {
+		return caption.Caption, nil
+	}
+
+	resp, err := llm.ChatVision(ctx, visionModel, &models.VisionChatRequest{
+		Model:     p.cfg.VisionModel,
+		MaxTokens: p.cfg.MaxTokens,
+		Messages: []models.VisionChatMessage{{
+			Role: "user",
+			Content: []models.ChatContentPart{
+				{Type: "text", Text: p.cfg.Prompt},
+				{Type: "image_url", ImageURL: &models.ChatImageURL{URL: image.URL}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if text == "" {
+		return "", errors.Wrapf(ErrInvalidInput, "模型没有返回图片[%s]的描述", image.ImageID)
+	}
+
+	caption := models.ImageCaption{
+		ImageID: image.ImageID,
+		TaskID:  taskID,
+		URL:     image.URL,
+		Caption: text,
+		ModelID: visionModel.ModelID,
+	}
+	if embeddingModel != nil {
+		vectors, err := EmbedBatches(ctx, embeddingModel, p.cfg.EmbeddingModel, []string{text}, 1, nil)
+		if err != nil {
+			return "", err
+		}
+		if err := p.store.Replace(ctx, image.ImageID, image.URL, []string{text}, vectors, embeddingModel.Dimensions); err != nil {
+			return "", err
+		}
+		caption.Embedded = true
+	}
+	if err := db.GetDBWithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&caption).Error; err != nil {
+		return "", errors.Wrapf(err, "保存图片[%s]描述失败", image.ImageID)
+	}
+	return text, nil
+}
+
+// existingCaption 查询同一图片地址已保存的描述
+func (p *ImageCaptioner) existingCaption(ctx context.Context, image models.ImageRef) (*models.ImageCaption, bool) {
+	var caption models.ImageCaption
+	if err := db.GetDBWithContext(ctx).Where("image_id = ?", image.ImageID).First(&caption).Error; err != nil {
+		return nil, false
+	}
+	if caption.URL != image.URL {
+		return nil, false
+	}
+	return &caption, true
+}
+
+func (p *ImageCaptioner) loadVisionModel(ctx context.Context) (*models.Model, error) {
+	var model models.Model
+	if err := db.GetDBWithContext(ctx).Where("model_id = ?", p.cfg.VisionModelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(ErrModelNotFound, "模型ID: %s", p.cfg.VisionModelID)
+		}
+		return nil, errors.Wrap(err, "查询图片描述模型失败")
+	}
+	return &model, nil
+}
+
+func summarizeStatus(items []models.ImageProcItem) string {
+	succeeded := 0
+	for _, item := range items {
+		if item.Status == models.ImageProcSuccess {
+			succeeded++
+		}
+	}
+	switch succeeded {
+	case len(items):
+		return models.ImageProcSuccess
+	case 0:
+		return models.ImageProcFailed
+	default:
+		return models.ImageProcPartial
+	}
+}
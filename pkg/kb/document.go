@@ -0,0 +1,134 @@
+package kb
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"myapi/pkg/ingest"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+var (
+	markdownImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	markdownHeading  = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	markdownFence    = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	markdownEmphasis = regexp.MustCompile(`(\*\*|__|~~)`)
+	blankLines       = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlBlockTags 结束时需要换行的块级元素
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "blockquote": true, "pre": true, "hr": true,
+}
+
+// htmlSkipTags 内容不参与检索的元素
+var htmlSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+// NormalizeFormat 规范化文档格式名称，空值视为纯文本，不支持的格式返回空字符串
+func NormalizeFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text", "txt", "plain", "text/plain":
+		return models.DocumentFormatText
+	case "markdown", "md", "text/markdown":
+		return models.DocumentFormatMarkdown
+	case "html", "htm", "text/html":
+		return models.DocumentFormatHTML
+	default:
+		return ""
+	}
+}
+
+// FormatFromFilename 按文件扩展名推断文档格式
+func FormatFromFilename(name string) string {
+	return NormalizeFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// ExtractText 按文档格式提取用于切分的纯文本，同时返回文档中的标题（Markdown一级标题或HTML的title）
+func ExtractText(format, content string) (title, text string, err error) {
+	switch NormalizeFormat(format) {
+	case models.DocumentFormatText:
+		text = content
+	case models.DocumentFormatMarkdown:
+		title, text = extractMarkdown(content)
+	case models.DocumentFormatHTML:
+		if title, text, err = extractHTML(content); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", errors.Wrapf(ingest.ErrInvalidInput, "不支持的文档格式: %s", format)
+	}
+	text = strings.TrimSpace(blankLines.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n"))
+	if text == "" {
+		return "", "", errors.Wrap(ingest.ErrInvalidInput, "文档没有可检索的文本内容")
+	}
+	return strings.TrimSpace(title), text, nil
+}
+
+// extractMarkdown 去掉Markdown的标记符号，保留段落结构
+func extractMarkdown(content string) (string, string) {
+	var title string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			title = strings.TrimPrefix(line, "# ")
+			break
+		}
+	}
+	text := markdownFence.ReplaceAllString(content, "")
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownHeading.ReplaceAllString(text, "")
+	text = markdownEmphasis.ReplaceAllString(text, "")
+	return title, text
+}
+
+// extractHTML 提取HTML中的可见文本，块级元素之间换行
+func extractHTML(content string) (string, string, error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", "", errors.Wrapf(ingest.ErrInvalidInput, "HTML解析失败: %v", err)
+	}
+	var title string
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.ElementNode:
+			if htmlSkipTags[n.Data] {
+				return
+			}
+			if n.Data == "title" {
+				if n.FirstChild != nil && title == "" {
+					title = n.FirstChild.Data
+				}
+				return
+			}
+		case html.TextNode:
+			if text := strings.TrimSpace(n.Data); text != "" {
+				sb.WriteString(strings.Join(strings.Fields(n.Data), " "))
+				sb.WriteString(" ")
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == html.ElementNode && htmlBlockTags[n.Data] {
+			sb.WriteString("\n\n")
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return title, strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,234 @@
+package kb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"myapi/pkg/chunker"
+	"myapi/pkg/db"
+	"myapi/pkg/ingest"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+	"myapi/pkg/vectorstore"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 知识库的默认分块参数和检索数量
+const (
+	DefaultChunkSize    = 500
+	DefaultChunkOverlap = 50
+	DefaultTopK         = 5
+
+	embedBatchSize = 16
+
+	// 重排序时默认召回topK的4倍且不少于20个候选分块
+	rerankCandidateFactor = 4
+	minRerankCandidates   = 20
+)
+
+// ErrNotRerankModel 指定的重排序模型不是rerank类型
+var ErrNotRerankModel = errors.New("模型不是重排序模型")
+
+// defaultSystemPrompt 知识库问答的默认系统提示词，参考资料追加在其后
+const defaultSystemPrompt = "你是知识库问答助手。请只根据下面的参考资料回答用户的问题，" +
+	"在用到资料的句子后用[编号]标注来源；参考资料中没有相关信息时，直接说明无法从知识库中找到答案，不要编造。"
+
+// NoAnswer 知识库中没有检索到任何分块时的回答
+const NoAnswer = "知识库中没有找到与问题相关的内容。"
+
+// Service 知识库的文档入库与检索问答
+type Service struct {
+	store vectorstore.Store
+}
+
+// NewService 创建知识库服务
+func NewService(store vectorstore.Store) *Service {
+	return &Service{store: store}
+}
+
+// AddDocument 切分、向量化文档正文并写入知识库的集合，返回分块数
+func (s *Service) AddDocument(ctx context.Context, kb *models.KnowledgeBase, documentID, title, text string) (int, error) {
+	model, err := ingest.LoadEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return 0, err
+	}
+	chunks := chunker.Split(text, kb.ChunkSize, kb.ChunkOverlap)
+	vectors, err := ingest.EmbedBatches(ctx, model, kb.EmbeddingModel, chunks, embedBatchSize, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.chunks(kb).Replace(ctx, documentID, title, chunks, vectors, model.Dimensions); err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// DeleteDocument 删除文档在知识库集合中的全部分块
+func (s *Service) DeleteDocument(ctx context.Context, kb *models.KnowledgeBase, documentID string) error {
+	return s.chunks(kb).Delete(ctx, documentID)
+}
+
+// ChunkCount 统计知识库集合中的分块数
+func (s *Service) ChunkCount(ctx context.Context, kb *models.KnowledgeBase) (int64, error) {
+	return s.chunks(kb).Count(ctx)
+}
+
+// Drop 删除知识库的集合
+func (s *Service) Drop(ctx context.Context, kb *models.KnowledgeBase) error {
+	return s.chunks(kb).Drop(ctx)
+}
+
+// Retrieve 检索与查询最相关的分块；指定了重排序模型时先召回更多候选分块，再按重排序结果保留topK个
+func (s *Service) Retrieve(ctx context.Context, kb *models.KnowledgeBase, query string, opts models.KBRetrieveOptions) ([]models.KBChunk, error) {
+	embeddingModel, err := ingest.LoadEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := ingest.EmbedBatches(ctx, embeddingModel, kb.EmbeddingModel, []string{query}, 1, nil)
+	if err != nil {
+		return nil, err
+	}
+	topK := opts.TopK
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
+	limit := topK
+	if opts.RerankModelID != "" {
+		limit = opts.Candidates
+		if limit <= 0 {
+			limit = max(topK*rerankCandidateFactor, minRerankCandidates)
+		}
+		limit = max(limit, topK)
+	}
+	hits, err := s.chunks(kb).Search(ctx, vectors[0], limit)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]models.KBChunk, len(hits))
+	for i, hit := range hits {
+		chunks[i] = models.KBChunk{
+			DocumentID: hit.DocID,
+			Title:      hit.Title,
+			ChunkIndex: hit.ChunkIndex,
+			Content:    hit.Content,
+			Score:      hit.Score,
+		}
+	}
+	if opts.RerankModelID == "" || len(chunks) == 0 {
+		return chunks, nil
+	}
+	return rerankChunks(ctx, opts.RerankModelID, query, chunks, topK)
+}
+
+// Query 检索与问题最相关的分块，并由对话模型基于检索结果回答，回答中的[编号]对应引用的序号
+func (s *Service) Query(ctx context.Context, kb *models.KnowledgeBase, chatModel *models.Model, req *models.KBQueryRequest) (*models.KBQueryResponse, error) {
+	chunks, err := s.Retrieve(ctx, kb, req.Question, req.KBRetrieveOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return &models.KBQueryResponse{Answer: NoAnswer, Citations: []models.KBCitation{}}, nil
+	}
+
+	citations := make([]models.KBCitation, len(chunks))
+	for i, chunk := range chunks {
+		citations[i] = models.KBCitation{Index: i + 1, KBChunk: chunk}
+	}
+
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultSystemPrompt
+	}
+	citations, messages, err := fitReferences(chatModel, req.Model, systemPrompt, req.Question, citations)
+	if err != nil {
+		return nil, err
+	}
+	chatReq := models.ChatRequest{Model: req.Model, Messages: messages}
+	chatResp, err := llm.Chat(ctx, chatModel, &chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := chatResp.Choices[0].Message.Content
+	for i := range citations {
+		citations[i].Cited = strings.Contains(answer, "["+strconv.Itoa(citations[i].Index)+"]")
+	}
+	return &models.KBQueryResponse{
+		Answer:    answer,
+		Citations: citations,
+		Usage:     chatResp.Usage,
+	}, nil
+}
+
+// rerankChunks 使用重排序模型对候选分块排序，保留相关性最高的topK个
+func rerankChunks(ctx context.Context, rerankModelID, query string, chunks []models.KBChunk, topK int) ([]models.KBChunk, error) {
+	var model models.Model
+	if err := db.GetDBWithContext(ctx).Where("model_id = ?", rerankModelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(ingest.ErrModelNotFound, "模型ID: %s", rerankModelID)
+		}
+		return nil, errors.Wrap(err, "查询重排序模型失败")
+	}
+	if !model.IsType(models.ModelTypeRerank) {
+		return nil, errors.Wrapf(ErrNotRerankModel, "模型%s的类型为%s", model.Name, model.Type)
+	}
+
+	documents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		documents[i] = chunk.Content
+	}
+	results, _, err := llm.Rerank(ctx, &model, "", query, documents, topK)
+	if err != nil {
+		return nil, err
+	}
+	reranked := make([]models.KBChunk, 0, len(results))
+	for _, r := range results {
+		chunk := chunks[r.Index]
+		score := r.RelevanceScore
+		chunk.RerankScore = &score
+		reranked = append(reranked, chunk)
+	}
+	return reranked, nil
+}
+
+func (s *Service) chunks(kb *models.KnowledgeBase) *ingest.ChunkStore {
+	return ingest.NewChunkStore(s.store, kb.Collection)
+}
+
+// fitReferences 组装问答消息，超出对话模型的上下文预算时从相似度最低的分块开始丢弃
+func fitReferences(chatModel *models.Model, upstreamModel, systemPrompt, question string, citations []models.KBCitation) ([]models.KBCitation, []models.ChatMessage, error) {
+	budget := llm.ContextBudget(chatModel)
+	count := llm.CounterFor(chatModel, upstreamModel)
+	for {
+		messages := []models.ChatMessage{
+			{Role: "system", Content: systemPrompt + "\n\n" + buildReferences(citations)},
+			{Role: "user", Content: question},
+		}
+		if budget == 0 {
+			return citations, messages, nil
+		}
+		tokens := llm.CountMessagesTokens(count, messages)
+		if tokens <= budget {
+			return citations, messages, nil
+		}
+		if len(citations) == 1 {
+			return nil, nil, &llm.ContextTooLongError{Tokens: tokens, Budget: budget}
+		}
+		citations = citations[:len(citations)-1]
+	}
+}
+
+// buildReferences 将检索到的分块编号后拼接为参考资料
+func buildReferences(citations []models.KBCitation) string {
+	var sb strings.Builder
+	sb.WriteString("参考资料：")
+	for _, c := range citations {
+		sb.WriteString(fmt.Sprintf("\n\n[%d] %s\n%s", c.Index, c.Title, c.Content))
+	}
+	return sb.String()
+}
@@ -0,0 +1,217 @@
+package kb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/ingest"
+	"myapi/pkg/models"
+	"myapi/pkg/vectorstore"
+
+	"github.com/google/uuid"
+)
+
+// fakeKeywords 伪造的向量化接口按关键词出现次数生成向量，最后一维恒为1
+var fakeKeywords = []string{"苹果", "香蕉", "橙子"}
+
+const fakeAnswer = "苹果是红色的[1]。"
+
+// fakeLLM OpenAI兼容接口的替身，记录收到的对话请求
+type fakeLLM struct {
+	*httptest.Server
+	mu    sync.Mutex
+	chats []models.ChatRequest
+}
+
+func newFakeLLM(t *testing.T) *fakeLLM {
+	f := &fakeLLM{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data := make([]map[string]any, len(req.Input))
+		for i, text := range req.Input {
+			vector := make([]float32, 0, len(fakeKeywords)+1)
+			for _, keyword := range fakeKeywords {
+				vector = append(vector, float32(strings.Count(text, keyword)))
+			}
+			data[i] = map[string]any{"index": i, "embedding": append(vector, 1)}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req models.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.chats = append(f.chats, req)
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"index": 0, "message": map[string]any{"role": "assistant", "content": fakeAnswer}}},
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLLM) chatRequests() []models.ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]models.ChatRequest(nil), f.chats...)
+}
+
+// createModel 在数据库中登记指向替身接口的模型，测试结束后删除
+func createModel(t *testing.T, modelType, endpoint string, dimensions int) *models.Model {
+	t.Helper()
+	model := &models.Model{
+		ModelID:    uuid.New().String(),
+		Name:       "kb-test-" + uuid.New().String(),
+		Endpoint:   endpoint,
+		APIKey:     "sk-test",
+		Timeout:    5,
+		Type:       modelType,
+		Dimensions: dimensions,
+		Enabled:    true,
+	}
+	if err := db.GetDB().Create(model).Error; err != nil {
+		t.Fatalf("创建模型失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.GetDB().Unscoped().Delete(&models.Model{}, "model_id = ?", model.ModelID)
+	})
+	return model
+}
+
+// newTestKB 创建绑定替身嵌入模型的知识库，集合保存在内存向量存储中
+func newTestKB(t *testing.T, dimensions int) (*Service, *models.KnowledgeBase, *models.Model, *fakeLLM) {
+	t.Helper()
+	dbtest.Setup(t)
+	llm := newFakeLLM(t)
+	embedding := createModel(t, "embedding", llm.URL+"/v1/embeddings", dimensions)
+	chat := &models.Model{
+		ModelID:  uuid.New().String(),
+		Name:     "kb-test-chat",
+		Endpoint: llm.URL + "/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     "chat",
+		Enabled:  true,
+	}
+	kb := &models.KnowledgeBase{
+		KBID:             uuid.New().String(),
+		EmbeddingModelID: embedding.ModelID,
+		Collection:       "kb_test",
+		ChunkSize:        DefaultChunkSize,
+		ChunkOverlap:     DefaultChunkOverlap,
+	}
+	return NewService(vectorstore.NewMemoryStore()), kb, chat, llm
+}
+
+func TestAddDocumentAndQuery(t *testing.T) {
+	service, kb, chat, llm := newTestKB(t, len(fakeKeywords)+1)
+	ctx := context.Background()
+
+	if _, err := service.AddDocument(ctx, kb, "apple", "苹果", "苹果是一种常见的水果，成熟的苹果通常是红色的。"); err != nil {
+		t.Fatalf("添加文档失败: %v", err)
+	}
+	if _, err := service.AddDocument(ctx, kb, "banana", "香蕉", "香蕉是黄色的，香蕉富含钾。"); err != nil {
+		t.Fatalf("添加文档失败: %v", err)
+	}
+
+	resp, err := service.Query(ctx, kb, chat, &models.KBQueryRequest{Question: "苹果是什么颜色？", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("知识库问答失败: %v", err)
+	}
+	if resp.Answer != fakeAnswer {
+		t.Errorf("回答为%q，期望%q", resp.Answer, fakeAnswer)
+	}
+	if len(resp.Citations) != 2 {
+		t.Fatalf("引用数为%d，期望2", len(resp.Citations))
+	}
+	if first := resp.Citations[0]; first.Index != 1 || first.DocumentID != "apple" || !first.Cited {
+		t.Errorf("第一条引用为%+v，期望被回答引用的apple文档", first)
+	}
+	if second := resp.Citations[1]; second.DocumentID != "banana" || second.Cited {
+		t.Errorf("第二条引用为%+v，期望未被引用的banana文档", second)
+	}
+	if resp.Citations[0].Score <= resp.Citations[1].Score {
+		t.Errorf("引用没有按相似度降序排列: %v <= %v", resp.Citations[0].Score, resp.Citations[1].Score)
+	}
+
+	chats := llm.chatRequests()
+	if len(chats) != 1 {
+		t.Fatalf("对话接口调用%d次，期望1次", len(chats))
+	}
+	messages := chats[0].Messages
+	if len(messages) != 2 || messages[0].Role != "system" || messages[1].Content != "苹果是什么颜色？" {
+		t.Fatalf("对话消息不符合预期: %+v", messages)
+	}
+	if !strings.Contains(messages[0].Content, "[1] 苹果\n苹果是一种常见的水果") {
+		t.Errorf("系统提示词中没有编号的参考资料: %q", messages[0].Content)
+	}
+}
+
+func TestAddDocumentReplacesChunks(t *testing.T) {
+	service, kb, chat, _ := newTestKB(t, len(fakeKeywords)+1)
+	kb.ChunkSize, kb.ChunkOverlap = 10, 0
+	ctx := context.Background()
+
+	n, err := service.AddDocument(ctx, kb, "fruit", "水果", "苹果很甜。\n\n香蕉很软。\n\n橙子很酸。")
+	if err != nil {
+		t.Fatalf("添加文档失败: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("分块数为%d，期望3", n)
+	}
+	if n, err = service.AddDocument(ctx, kb, "fruit", "水果", "苹果很甜。"); err != nil || n != 1 {
+		t.Fatalf("重新添加文档返回%d, %v，期望1个分块", n, err)
+	}
+
+	resp, err := service.Query(ctx, kb, chat, &models.KBQueryRequest{Question: "橙子", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("知识库问答失败: %v", err)
+	}
+	if len(resp.Citations) != 1 || resp.Citations[0].Content != "苹果很甜。" {
+		t.Errorf("重新添加后检索到%+v，期望只剩新的分块", resp.Citations)
+	}
+}
+
+func TestQueryEmptyKnowledgeBase(t *testing.T) {
+	service, kb, chat, llm := newTestKB(t, len(fakeKeywords)+1)
+
+	resp, err := service.Query(context.Background(), kb, chat, &models.KBQueryRequest{Question: "苹果", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("知识库问答失败: %v", err)
+	}
+	if resp.Answer != NoAnswer || len(resp.Citations) != 0 {
+		t.Errorf("空知识库返回%+v，期望固定的无答案回复", resp)
+	}
+	if chats := llm.chatRequests(); len(chats) != 0 {
+		t.Errorf("空知识库调用了%d次对话接口", len(chats))
+	}
+}
+
+func TestAddDocumentDimensionMismatch(t *testing.T) {
+	service, kb, _, _ := newTestKB(t, 8)
+
+	_, err := service.AddDocument(context.Background(), kb, "apple", "苹果", "苹果是红色的。")
+	if !errors.Is(err, ingest.ErrDimensionMismatch) {
+		t.Errorf("返回错误%v，期望向量维度不一致", err)
+	}
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"net/http"
+	"sync"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// ErrModelUnavailable 模型被健康检查标记为down，请求不再转发给上游
+var ErrModelUnavailable = errors.New("模型当前不可用")
+
+// downModels 被健康检查标记为down的模型ID
+var downModels sync.Map
+
+// SetModelDown 由健康检查更新模型是否不可用
+func SetModelDown(modelID string, down bool) {
+	if down {
+		downModels.Store(modelID, struct{}{})
+	} else {
+		downModels.Delete(modelID)
+	}
+}
+
+// IsModelDown 判断模型是否被标记为不可用
+func IsModelDown(modelID string) bool {
+	_, ok := downModels.Load(modelID)
+	return ok
+}
+
+// 停用或删除的模型不再转发请求
+var (
+	ErrModelDisabled = errors.New("模型已停用")
+	ErrModelDeleted  = errors.New("模型已删除")
+)
+
+// CheckModelState 校验模型未被删除、停用或被健康检查标记为down
+func CheckModelState(model *models.Model) error {
+	switch {
+	case model.DeletedAt.Valid:
+		return errors.Wrapf(ErrModelDeleted, "模型%s", model.Name)
+	case !model.Enabled:
+		return errors.Wrapf(ErrModelDisabled, "模型%s", model.Name)
+	case IsModelDown(model.ModelID):
+		return errors.Wrapf(ErrModelUnavailable, "模型%s", model.Name)
+	}
+	return nil
+}
+
+// RejectionStatus 返回请求因模型的类型、能力或状态被拒绝时对应的HTTP状态码，其他错误返回false
+func RejectionStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, ErrUnsupportedRequest):
+		return http.StatusBadRequest, true
+	case errors.Is(err, ErrModelDisabled):
+		return http.StatusForbidden, true
+	case errors.Is(err, ErrModelDeleted):
+		return http.StatusGone, true
+	case errors.Is(err, ErrModelUnavailable):
+		return http.StatusServiceUnavailable, true
+	}
+	return 0, false
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupportedRequest 模型的类型或能力不支持该请求
+var ErrUnsupportedRequest = errors.New("模型不支持该请求")
+
+// CheckChatRequest 转发前校验模型是否为对话模型，以及是否具备请求用到的流式、工具调用和JSON模式能力
+func CheckChatRequest(model *models.Model, req *models.ChatRequest) error {
+	if err := checkChatModel(model); err != nil {
+		return err
+	}
+	if req.Stream && !model.SupportsStreaming {
+		return errors.Wrapf(ErrUnsupportedRequest, "模型%s不支持流式响应", model.Name)
+	}
+	if (hasJSON(req.Tools) || hasJSON(req.ToolChoice)) && !model.SupportsTools {
+		return errors.Wrapf(ErrUnsupportedRequest, "模型%s不支持工具调用", model.Name)
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != "" && req.ResponseFormat.Type != "text" && !model.SupportsJSONMode {
+		return errors.Wrapf(ErrUnsupportedRequest, "模型%s不支持JSON模式", model.Name)
+	}
+	return nil
+}
+
+// CheckVisionRequest 校验模型是否支持图文消息
+func CheckVisionRequest(model *models.Model) error {
+	if err := checkChatModel(model); err != nil {
+		return err
+	}
+	if !model.SupportsVision {
+		return errors.Wrapf(ErrUnsupportedRequest, "模型%s不支持图片输入", model.Name)
+	}
+	return nil
+}
+
+// checkChatModel 拒绝已知的非对话类型模型，未知的历史类型按对话模型处理
+func checkChatModel(model *models.Model) error {
+	for _, t := range models.ModelTypes() {
+		if t != models.ModelTypeChat && model.IsType(t) {
+			return errors.Wrapf(ErrUnsupportedRequest, "模型%s的类型为%s，不是对话模型", model.Name, model.Type)
+		}
+	}
+	return nil
+}
+
+func hasJSON(raw []byte) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrEmptyAPIKey   = errors.New("API Key 为空")
+	ErrInvalidAPIKey = errors.New("API Key 包含非法字符")
+)
+
+// UpstreamError 大模型接口返回的非2xx响应
+type UpstreamError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("大模型接口返回错误[%d]: %s", e.StatusCode, string(e.Body))
+}
+
+// IsPermanentError 判断调用大模型的错误是否无法通过重试恢复：API Key 不合法、模型不支持该请求、模型已停用或删除、上游返回4xx（429除外）
+func IsPermanentError(err error) bool {
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		return upstream.StatusCode >= 400 && upstream.StatusCode < 500 && upstream.StatusCode != http.StatusTooManyRequests
+	}
+	return errors.Is(err, ErrEmptyAPIKey) || errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrUnsupportedRequest) ||
+		errors.Is(err, ErrModelDisabled) || errors.Is(err, ErrModelDeleted)
+}
+
+// Do 向模型的 endpoint 发送 JSON 请求，调用方负责关闭响应体；已删除、停用或被健康检查标记为down的模型直接返回错误
+func Do(ctx context.Context, model *models.Model, payload any) (*http.Response, error) {
+	if err := CheckModelState(model); err != nil {
+		return nil, err
+	}
+	return send(ctx, model, payload)
+}
+
+func send(ctx context.Context, model *models.Model, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "请求序列化失败")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, model.Endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "请求创建失败")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	apiKey := strings.TrimSpace(model.APIKey)
+	if apiKey == "" {
+		return nil, ErrEmptyAPIKey
+	}
+	if strings.ContainsAny(apiKey, "\r\n\t ") {
+		return nil, ErrInvalidAPIKey
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := http.DefaultClient
+	if model.Timeout > 0 {
+		client = &http.Client{Timeout: time.Duration(model.Timeout) * time.Second}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "大模型请求失败")
+	}
+	return resp, nil
+}
+
+// Chat 调用 OpenAI 兼容的对话接口并解析响应
+func Chat(ctx context.Context, model *models.Model, req *models.ChatRequest) (*models.ChatResponse, error) {
+	if req.Stream {
+		return nil, errors.Wrap(ErrUnsupportedRequest, "该接口不支持流式响应")
+	}
+	if err := CheckChatRequest(model, req); err != nil {
+		return nil, err
+	}
+	return complete(ctx, model, req)
+}
+
+// ChatVision 调用 OpenAI 兼容的对话接口发送图文消息并解析响应
+func ChatVision(ctx context.Context, model *models.Model, req *models.VisionChatRequest) (*models.ChatResponse, error) {
+	if err := CheckVisionRequest(model); err != nil {
+		return nil, err
+	}
+	return complete(ctx, model, req)
+}
+
+func complete(ctx context.Context, model *models.Model, payload any) (*models.ChatResponse, error) {
+	resp, err := Do(ctx, model, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取大模型响应失败")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UpstreamError{StatusCode: resp.StatusCode, Body: body}
+	}
+	var chatResp models.ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, errors.Wrap(err, "解析大模型响应失败")
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, errors.New("大模型响应中没有可用的回复")
+	}
+	return &chatResp, nil
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// ContextTooLongError 消息超出模型上下文窗口且无法按策略压缩
+type ContextTooLongError struct {
+	Tokens int
+	Budget int
+}
+
+func (e *ContextTooLongError) Error() string {
+	return fmt.Sprintf("对话内容约%d个token，超出模型可用的上下文长度%d", e.Tokens, e.Budget)
+}
+
+// Summarizer 将较早的对话消息总结为一段文本
+type Summarizer func(ctx context.Context, messages []models.ChatMessage) (string, error)
+
+// NewModelSummarizer 使用指定的模型生成摘要
+func NewModelSummarizer(model *models.Model, upstreamModel string) Summarizer {
+	return func(ctx context.Context, messages []models.ChatMessage) (string, error) {
+		var sb strings.Builder
+		for _, m := range messages {
+			sb.WriteString(m.Role)
+			sb.WriteString(": ")
+			sb.WriteString(m.Content)
+			sb.WriteString("\n")
+		}
+		resp, err := Chat(ctx, model, &models.ChatRequest{
+			Model: upstreamModel,
+			Messages: []models.ChatMessage{
+				{Role: "system", Content: "请简洁地总结以下对话的要点，保留关键事实、结论和未解决的问题，只输出摘要内容。"},
+				{Role: "user", Content: sb.String()},
+			},
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "生成对话摘要失败")
+		}
+		return resp.Choices[0].Message.Content, nil
+	}
+}
+
+// ContextBudget 返回模型可用于输入消息的token数，0表示不限制
+func ContextBudget(model *models.Model) int {
+	if model.ContextWindow <= 0 {
+		return 0
+	}
+	budget := model.ContextWindow - model.MaxOutputTokens
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// SummarizerLoader 按model_id加载摘要模型，模型不存在时返回nil
+type SummarizerLoader func(modelID string) (*models.Model, error)
+
+// FitContext 按模型配置的策略使消息适配上下文窗口，loadSummarizer 由调用方提供，只在 summarize 策略需要压缩时调用
+func FitContext(ctx context.Context, model *models.Model, upstreamModel string, messages []models.ChatMessage, loadSummarizer SummarizerLoader) ([]models.ChatMessage, error) {
+	budget := ContextBudget(model)
+	if budget == 0 {
+		return messages, nil
+	}
+	count := CounterFor(model, upstreamModel)
+	tokens := CountMessagesTokens(count, messages)
+	if tokens <= budget {
+		return messages, nil
+	}
+	switch model.ContextStrategy {
+	case models.ContextStrategyTruncate:
+		return truncateMessages(count, messages, budget)
+	case models.ContextStrategySummarize:
+		summarizerModel, err := loadSummarizer(model.SummarizerModelID)
+		if err != nil {
+			return nil, err
+		}
+		// 摘要模型被删除时报错，不静默退化为截断，避免对话内容在运维无感知的情况下丢失
+		if summarizerModel == nil {
+			return nil, errors.Errorf("模型%s的摘要模型%s不存在或已删除", model.ModelID, model.SummarizerModelID)
+		}
+		return summarizeMessages(ctx, count, messages, budget, NewModelSummarizer(summarizerModel, model.SummarizerModel))
+	default:
+		return nil, &ContextTooLongError{Tokens: tokens, Budget: budget}
+	}
+}
+
+// splitSystem 拆分开头的系统提示词和其余消息
+func splitSystem(messages []models.ChatMessage) ([]models.ChatMessage, []models.ChatMessage) {
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		i++
+	}
+	return messages[:i], messages[i:]
+}
+
+// truncateMessages 保留系统提示词，从最早的消息开始丢弃直到满足预算，最后一条消息始终保留
+func truncateMessages(count TokenCounter, messages []models.ChatMessage, budget int) ([]models.ChatMessage, error) {
+	system, rest := splitSystem(messages)
+	for len(rest) > 1 {
+		candidate := append(append([]models.ChatMessage{}, system...), rest...)
+		if CountMessagesTokens(count, candidate) <= budget {
+			return candidate, nil
+		}
+		rest = rest[1:]
+	}
+	result := append(append([]models.ChatMessage{}, system...), rest...)
+	if tokens := CountMessagesTokens(count, result); tokens > budget {
+		return nil, &ContextTooLongError{Tokens: tokens, Budget: budget}
+	}
+	return result, nil
+}
+
+// summarizeMessages 保留系统提示词和尽可能多的近期消息，其余较早的消息总结为一条系统消息
+func summarizeMessages(ctx context.Context, count TokenCounter, messages []models.ChatMessage, budget int, summarizer Summarizer) ([]models.ChatMessage, error) {
+	system, rest := splitSystem(messages)
+
+	// 近期消息最多占用一半预算，为摘要留出空间
+	keep := 0
+	used := CountMessagesTokens(count, system)
+	for keep < len(rest) {
+		m := rest[len(rest)-1-keep]
+		cost := countMessageTokens(count, m)
+		if keep > 0 && used+cost > budget/2 {
+			break
+		}
+		used += cost
+		keep++
+	}
+	older, recent := rest[:len(rest)-keep], rest[len(rest)-keep:]
+	if len(older) == 0 {
+		return truncateMessages(count, messages, budget)
+	}
+
+	summary, err := summarizer(ctx, older)
+	if err != nil {
+		return nil, err
+	}
+	result := append([]models.ChatMessage{}, system...)
+	result = append(result, models.ChatMessage{Role: "system", Content: "以下是之前对话的摘要：\n" + summary})
+	result = append(result, recent...)
+	if CountMessagesTokens(count, result) > budget {
+		return truncateMessages(count, result, budget)
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// Embed 调用 OpenAI 兼容的向量化接口，返回的向量与输入一一对应；upstreamModel为空时使用模型名称
+func Embed(ctx context.Context, model *models.Model, upstreamModel string, inputs []string) ([][]float32, error) {
+	if upstreamModel == "" {
+		upstreamModel = model.Name
+	}
+	resp, err := Do(ctx, model, models.EmbeddingRequest{Model: upstreamModel, Input: inputs})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取向量化响应失败")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UpstreamError{StatusCode: resp.StatusCode, Body: body}
+	}
+	var embResp models.EmbeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, errors.Wrap(err, "解析向量化响应失败")
+	}
+	if len(embResp.Data) != len(inputs) {
+		return nil, errors.Errorf("向量化响应数量%d与输入数量%d不一致", len(embResp.Data), len(inputs))
+	}
+	vectors := make([][]float32, len(inputs))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, errors.Errorf("向量化响应的序号%d超出范围", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// probeInput 连通性测试发送的输入内容
+const probeInput = "ping"
+
+// maxProbeErrorBody 测试结果中保留的上游错误响应长度
+const maxProbeErrorBody = 512
+
+// probeChatRequest 最小的对话请求，只生成1个token
+type probeChatRequest struct {
+	Model     string               `json:"model"`
+	Messages  []models.ChatMessage `json:"messages"`
+	MaxTokens int                  `json:"max_tokens"`
+}
+
+// probeResponse 兼容对话、向量化和重排序响应中测试需要的字段
+type probeResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Probe 按模型类型向上游发送最小的对话、向量化或重排序请求，返回连通性、鉴权、耗时等结果；
+// upstreamModel为空时使用模型名称，不支持测试的模型类型返回ErrUnsupportedRequest
+func Probe(ctx context.Context, model *models.Model, upstreamModel string) (*models.ModelTestResult, error) {
+	if upstreamModel == "" {
+		upstreamModel = model.Name
+	}
+	var payload any
+	switch {
+	case model.IsType(models.ModelTypeEmbedding):
+		payload = models.EmbeddingRequest{Model: upstreamModel, Input: []string{probeInput}}
+	case model.IsType(models.ModelTypeRerank):
+		payload = models.UpstreamRerankRequest{Model: upstreamModel, Query: probeInput, Documents: []string{probeInput}, TopN: 1}
+	case checkChatModel(model) == nil:
+		payload = probeChatRequest{
+			Model:     upstreamModel,
+			Messages:  []models.ChatMessage{{Role: "user", Content: probeInput}},
+			MaxTokens: 1,
+		}
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedRequest, "暂不支持测试%s类型的模型", model.Type)
+	}
+
+	result := &models.ModelTestResult{}
+	start := time.Now()
+	// 探测不受down标记限制，否则标记为down的模型无法恢复
+	resp, err := send(ctx, model, payload)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+	result.Authorized = resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden
+	if err != nil {
+		result.Error = errors.Wrap(err, "读取响应失败").Error()
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(body) > maxProbeErrorBody {
+			body = body[:maxProbeErrorBody]
+		}
+		result.Error = (&UpstreamError{StatusCode: resp.StatusCode, Body: body}).Error()
+		return result, nil
+	}
+
+	var parsed probeResponse
+	// TEI 的重排序接口返回数组，没有模型名
+	_ = json.Unmarshal(body, &parsed)
+	result.ReturnedModel = parsed.Model
+	result.Success = true
+	if model.IsType(models.ModelTypeEmbedding) {
+		if len(parsed.Data) > 0 {
+			result.Dimensions = len(parsed.Data[0].Embedding)
+		}
+		result.ExpectedDimensions = model.Dimensions
+		match := result.Dimensions == model.Dimensions
+		result.DimensionsMatch = &match
+		if !match {
+			result.Success = false
+			result.Error = fmt.Sprintf("向量维度为%d，与配置的%d不一致", result.Dimensions, model.Dimensions)
+		}
+	}
+	return result, nil
+}
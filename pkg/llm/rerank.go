@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+// upstreamRerankResult 上游返回的单条结果，Cohere/Jina使用relevance_score，TEI使用score
+type upstreamRerankResult struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float64 `json:"relevance_score"`
+	Score          *float64 `json:"score"`
+}
+
+// upstreamRerankResponse 上游的重排序响应，兼容Cohere的meta和Jina的usage
+type upstreamRerankResponse struct {
+	Results []upstreamRerankResult `json:"results"`
+	Usage   struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	Meta struct {
+		BilledUnits struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// Rerank 调用 Cohere/Jina 兼容的重排序接口，返回按相关性降序排列的结果；
+// 分数按模型配置的score_normalization归一化，topN为0时返回全部文档
+func Rerank(ctx context.Context, model *models.Model, upstreamModel, query string, documents []string, topN int) ([]models.RerankResult, int, error) {
+	if upstreamModel == "" {
+		upstreamModel = model.Name
+	}
+	resp, err := Do(ctx, model, models.UpstreamRerankRequest{
+		Model:     upstreamModel,
+		Query:     query,
+		Documents: documents,
+		TopN:      topN,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "读取重排序响应失败")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, 0, &UpstreamError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	var rerankResp upstreamRerankResponse
+	if err := json.Unmarshal(body, &rerankResp); err != nil || rerankResp.Results == nil {
+		// TEI 的 /rerank 直接返回结果数组
+		if e := json.Unmarshal(body, &rerankResp.Results); e != nil {
+			return nil, 0, errors.Wrap(e, "解析重排序响应失败")
+		}
+	}
+
+	results := make([]models.RerankResult, 0, len(rerankResp.Results))
+	for _, r := range rerankResp.Results {
+		if r.Index < 0 || r.Index >= len(documents) {
+			return nil, 0, errors.Errorf("重排序响应的序号%d超出范围", r.Index)
+		}
+		score := r.RelevanceScore
+		if score == nil {
+			score = r.Score
+		}
+		if score == nil {
+			return nil, 0, errors.Errorf("重排序响应的第%d个结果没有分数", r.Index)
+		}
+		results = append(results, models.RerankResult{Index: r.Index, RelevanceScore: *score})
+	}
+	normalizeScores(model, results)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	if topN > 0 && len(results) > topN {
+		results = results[:topN]
+	}
+
+	usage := rerankResp.Usage.TotalTokens
+	if usage == 0 {
+		usage = rerankResp.Meta.BilledUnits.InputTokens
+	}
+	return results, usage, nil
+}
+
+// normalizeScores 按模型配置的方式归一化分数，同一模型的分数始终可比，不随单次响应的取值范围变化
+func normalizeScores(model *models.Model, results []models.RerankResult) {
+	if model.ScoreNormalization != models.ScoreNormalizationSigmoid {
+		return
+	}
+	for i := range results {
+		results[i].RelevanceScore = 1 / (1 + math.Exp(-results[i].RelevanceScore))
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"myapi/pkg/models"
+	"myapi/pkg/tokenizer"
+
+	"go.uber.org/zap"
+)
+
+// TokenCounter 计算一段文本的token数
+type TokenCounter func(text string) int
+
+// EstimateTokens 粗略估算文本的token数量：
+// ASCII字符约4个对应1个token，其余字符（中文等）按每个字符1个token计算
+func EstimateTokens(text string) int {
+	ascii, others := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else if !unicode.IsSpace(r) {
+			others++
+		}
+	}
+	return (ascii+3)/4 + others
+}
+
+// EstimateMessagesTokens 估算一组对话消息的token数量
+func EstimateMessagesTokens(messages []models.ChatMessage) int {
+	return CountMessagesTokens(EstimateTokens, messages)
+}
+
+// CountMessagesTokens 使用指定的计数器计算一组对话消息的token数量
+func CountMessagesTokens(count TokenCounter, messages []models.ChatMessage) int {
+	total := tokenizer.TokensPerReply
+	for _, m := range messages {
+		total += countMessageTokens(count, m)
+	}
+	return total
+}
+
+func countMessageTokens(count TokenCounter, m models.ChatMessage) int {
+	return tokenizer.TokensPerMessage + count(m.Role) + count(m.Content)
+}
+
+// CounterFor 返回模型对应的token计数器，分词器不可用时退回到本地估算
+func CounterFor(model *models.Model, upstreamModel string) TokenCounter {
+	t, err := tokenizer.Get(tokenizer.ForModel(model, upstreamModel))
+	if err != nil {
+		zap.S().Warnf("加载模型[%s]的分词器失败，使用估算值: %v", model.ModelID, err)
+		return EstimateTokens
+	}
+	return t.Count
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"myapi/pkg/util"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "myapi"
+
+var (
+	registry     = prometheus.NewRegistry()
+	registerOnce sync.Once
+
+	// buildInfo 构建信息指标，值恒为1，构建元数据放在标签中
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "A metric with a constant '1' value labeled by version, git commit, git tag, tree state, build date, go version and platform.",
+	}, []string{"version", "git_commit", "git_tag", "git_tree_state", "build_date", "go_version", "platform"})
+)
+
+// Registry 返回服务使用的指标注册表
+func Registry() *prometheus.Registry {
+	register()
+	return registry
+}
+
+// Handler 返回 /metrics 的 http 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry(), promhttp.HandlerOpts{Registry: registry})
+}
+
+func register() {
+	registerOnce.Do(func() {
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			buildInfo,
+		)
+		v := util.GetVersion()
+		buildInfo.WithLabelValues(v.Version, v.GitCommit, v.GitTag, v.GitTreeState, v.BuildDate, v.GoVersion, v.Platform).Set(1)
+	})
+}
@@ -0,0 +1,24 @@
+package milvus
+
+import (
+	"context"
+
+	"myapi/config"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/pkg/errors"
+)
+
+// Connect 按配置连接Milvus
+func Connect(ctx context.Context, cfg *config.MilvusConfig) (client.Client, error) {
+	cli, err := client.NewClient(ctx, client.Config{
+		Address:  cfg.Host,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DBName:   cfg.DBName,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "连接Milvus[%s]失败", cfg.Host)
+	}
+	return cli, nil
+}
@@ -0,0 +1,9 @@
+package models
+
+// Article 通过消息队列投递的待入库文章
+type Article struct {
+	ArticleID string `json:"article_id" binding:"required"`
+	Title     string `json:"title"`
+	Content   string `json:"content" binding:"required"`
+	URL       string `json:"url"`
+}
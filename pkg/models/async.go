@@ -0,0 +1,15 @@
+package models
+
+// AsyncChatRequest 通过消息队列提交的对话请求，ReplyTo为空时使用消息头中的回复主题
+type AsyncChatRequest struct {
+	RequestID string `json:"request_id"`
+	ModelID   string `json:"model_id" binding:"required"`
+	ReplyTo   string `json:"reply_to"`
+	ChatRequest
+}
+
+// AsyncChatReply 发布到回复主题的对话结果，Data为大模型的响应
+type AsyncChatReply struct {
+	RequestID string `json:"request_id"`
+	APIResponse
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog 一次管理操作的审计事件
+type AuditLog struct {
+	AuditID   string    `json:"audit_id" gorm:"primaryKey;type:varchar(64)"`
+	RequestID string    `json:"request_id" gorm:"type:varchar(64);index"`
+	Actor     string    `json:"actor" gorm:"type:varchar(255);index"`
+	ClientIP  string    `json:"client_ip" gorm:"type:varchar(64)"`
+	Action    string    `json:"action" gorm:"type:varchar(64);index"` // 操作名称，如UpdateModel
+	Method    string    `json:"method" gorm:"type:varchar(16)"`
+	Route     string    `json:"route" gorm:"type:varchar(255)"` // 路由模板，如/api/v1/models/:id
+	Path      string    `json:"path" gorm:"type:varchar(1024)"`
+	ModelID   string    `json:"model_id" gorm:"type:varchar(64);index"` // 操作的模型ID，非模型操作为空
+	Status    int       `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditLogQuery 查询审计日志的参数，Start和End为RFC3339格式
+type AuditLogQuery struct {
+	Actor    string    `form:"actor"`
+	Action   string    `form:"action"`
+	ModelID  string    `form:"model_id"`
+	Start    time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End      time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int       `form:"page"`
+	PageSize int       `form:"page_size"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "t_audit_log"
+}
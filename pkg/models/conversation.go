@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Conversation 表示一次持久化的多轮对话
+type Conversation struct {
+	ConversationID string    `json:"conversation_id" gorm:"primaryKey;type:varchar(64)"`
+	Title          string    `json:"title" gorm:"type:varchar(255)"`
+	ModelID        string    `json:"model_id" gorm:"type:varchar(64);not null;index"`
+	Model          string    `json:"model" gorm:"type:varchar(255);not null"`
+	SystemPrompt   string    `json:"system_prompt" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Message 表示对话中的一条消息
+type Message struct {
+	MessageID        string    `json:"message_id" gorm:"primaryKey;type:varchar(64)"`
+	ConversationID   string    `json:"conversation_id" gorm:"type:varchar(64);not null;index"`
+	Role             string    `json:"role" gorm:"type:varchar(32);not null"`
+	Content          string    `json:"content" gorm:"type:longtext;not null"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CreateConversationRequest 创建对话的请求结构
+type CreateConversationRequest struct {
+	ModelID      string `json:"model_id" binding:"required"`
+	Model        string `json:"model" binding:"required"`
+	Title        string `json:"title"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+// SendMessageRequest 向对话发送消息的请求结构
+type SendMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// SendMessageResponse 向对话发送消息的响应结构
+type SendMessageResponse struct {
+	UserMessage      Message   `json:"user_message"`
+	AssistantMessage Message   `json:"assistant_message"`
+	Usage            ChatUsage `json:"usage"`
+}
+
+// TableName 指定表名
+func (Conversation) TableName() string {
+	return "t_conversation"
+}
+
+// TableName 指定表名
+func (Message) TableName() string {
+	return "t_message"
+}
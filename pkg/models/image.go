@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// 图片预处理的状态
+const (
+	ImageProcSuccess = "success"
+	ImageProcPartial = "partial"
+	ImageProcFailed  = "failed"
+)
+
+// ImageRef 待处理的图片
+type ImageRef struct {
+	ImageID string `json:"image_id" binding:"required"`
+	URL     string `json:"url" binding:"required"`
+}
+
+// ImageProcNotification 图片预处理通知，ReplyTo为空时使用消息头或配置的完成通知主题
+type ImageProcNotification struct {
+	TaskID  string     `json:"task_id" binding:"required"`
+	Images  []ImageRef `json:"images" binding:"required,min=1,dive"`
+	ReplyTo string     `json:"reply_to"`
+}
+
+// ImageProcItem 单张图片的处理结果
+type ImageProcItem struct {
+	ImageID string `json:"image_id"`
+	Status  string `json:"status"`
+	Caption string `json:"caption,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ImageProcResult 图片预处理完成通知
+type ImageProcResult struct {
+	TaskID string          `json:"task_id"`
+	Status string          `json:"status"`
+	Images []ImageProcItem `json:"images"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ImageCaption 图片的描述文本，同一图片重复处理时覆盖
+type ImageCaption struct {
+	ImageID   string    `json:"image_id" gorm:"primaryKey;type:varchar(255)"`
+	TaskID    string    `json:"task_id" gorm:"type:varchar(255);not null;index"`
+	URL       string    `json:"url" gorm:"type:text;not null"`
+	Caption   string    `json:"caption" gorm:"type:text;not null"`
+	ModelID   string    `json:"model_id" gorm:"type:varchar(64);not null"`
+	Embedded  bool      `json:"embedded" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (ImageCaption) TableName() string {
+	return "t_image_caption"
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+)
+
+// 知识库文档支持的格式
+const (
+	DocumentFormatText     = "text"
+	DocumentFormatMarkdown = "markdown"
+	DocumentFormatHTML     = "html"
+)
+
+// KnowledgeBase 知识库，绑定一个嵌入模型和一个向量集合
+type KnowledgeBase struct {
+	KBID             string    `json:"kb_id" gorm:"primaryKey;type:varchar(64)"`
+	Name             string    `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Description      string    `json:"description" gorm:"type:varchar(1024)"`
+	EmbeddingModelID string    `json:"embedding_model_id" gorm:"type:varchar(64);not null"`
+	EmbeddingModel   string    `json:"embedding_model" gorm:"type:varchar(255)"`
+	Collection       string    `json:"collection" gorm:"type:varchar(255);not null;uniqueIndex"`
+	ChunkSize        int       `json:"chunk_size" gorm:"not null"`
+	ChunkOverlap     int       `json:"chunk_overlap" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// KBDocument 知识库中的一篇文档，正文切分后的分块保存在向量集合中
+type KBDocument struct {
+	DocumentID string    `json:"document_id" gorm:"primaryKey;type:varchar(64)"`
+	KBID       string    `json:"kb_id" gorm:"type:varchar(64);not null;index"`
+	Title      string    `json:"title" gorm:"type:varchar(1024)"`
+	Format     string    `json:"format" gorm:"type:varchar(32);not null"`
+	ChunkCount int       `json:"chunk_count"`
+	CharCount  int       `json:"char_count"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CreateKnowledgeBaseRequest 创建知识库的请求结构，Collection为空时自动生成
+type CreateKnowledgeBaseRequest struct {
+	Name             string `json:"name" binding:"required"`
+	Description      string `json:"description"`
+	EmbeddingModelID string `json:"embedding_model_id" binding:"required"`
+	EmbeddingModel   string `json:"embedding_model"`
+	Collection       string `json:"collection"`
+	ChunkSize        int    `json:"chunk_size" binding:"gte=0"`
+	ChunkOverlap     int    `json:"chunk_overlap" binding:"gte=0"`
+}
+
+// AddKBDocumentRequest 上传文档的请求结构，Format为空时按纯文本处理
+type AddKBDocumentRequest struct {
+	Title   string `json:"title"`
+	Format  string `json:"format"`
+	Content string `json:"content" binding:"required"`
+}
+
+// KBRetrieveOptions 知识库检索参数，RerankModelID不为空时先召回Candidates个分块，重排序后保留TopK个
+type KBRetrieveOptions struct {
+	TopK          int    `json:"top_k" binding:"gte=0,lte=50"`
+	RerankModelID string `json:"rerank_model_id"`
+	Candidates    int    `json:"candidates" binding:"gte=0,lte=200"`
+}
+
+// KBQueryRequest 知识库问答的请求结构，ModelID和Model为回答使用的对话模型
+type KBQueryRequest struct {
+	KBRetrieveOptions
+	Question     string `json:"question" binding:"required"`
+	ModelID      string `json:"model_id" binding:"required"`
+	Model        string `json:"model" binding:"required"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+// KBSearchRequest 知识库检索的请求结构，只返回相关分块不调用对话模型
+type KBSearchRequest struct {
+	KBRetrieveOptions
+	Query string `json:"query" binding:"required"`
+}
+
+// KBChunk 检索命中的知识库分块，Score为向量相似度，RerankScore为重排序后的相关性分数
+type KBChunk struct {
+	DocumentID  string   `json:"document_id"`
+	Title       string   `json:"title"`
+	ChunkIndex  int      `json:"chunk_index"`
+	Content     string   `json:"content"`
+	Score       float32  `json:"score"`
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+}
+
+// KBCitation 回答引用的知识库分块，Index对应回答中的[编号]
+type KBCitation struct {
+	Index int `json:"index"`
+	KBChunk
+	Cited bool `json:"cited"`
+}
+
+// KBSearchResponse 知识库检索的响应结构
+type KBSearchResponse struct {
+	Chunks []KBChunk `json:"chunks"`
+}
+
+// KBQueryResponse 知识库问答的响应结构
+type KBQueryResponse struct {
+	Answer    string       `json:"answer"`
+	Citations []KBCitation `json:"citations"`
+	Usage     ChatUsage    `json:"usage"`
+}
+
+// TableName 指定表名
+func (KnowledgeBase) TableName() string {
+	return "t_knowledge_base"
+}
+
+// TableName 指定表名
+func (KBDocument) TableName() string {
+	return "t_kb_document"
+}
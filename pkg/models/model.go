@@ -1,7 +1,13 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Model 表示AI模型的数据结构
@@ -12,9 +18,118 @@ type Model struct {
 	APIKey     string    `json:"api_key" gorm:"type:varchar(255);not null" binding:"required"`
 	Timeout    int       `json:"timeout" gorm:"not null" binding:"required"`
 	Type       string    `json:"type" gorm:"type:varchar(255);not null" binding:"required"`
-	Dimensions int       `json:"dimensions" gorm:"not null" binding:"required"`
+	Dimensions int       `json:"dimensions" gorm:"not null;default:0"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 上下文窗口配置，ContextWindow为0表示不限制
+	ContextWindow     int    `json:"context_window" gorm:"not null;default:0"`
+	MaxOutputTokens   int    `json:"max_output_tokens" gorm:"not null;default:0"`
+	ContextStrategy   string `json:"context_strategy" gorm:"type:varchar(32);not null;default:''"`
+	SummarizerModelID string `json:"summarizer_model_id" gorm:"type:varchar(64)"`
+	SummarizerModel   string `json:"summarizer_model" gorm:"type:varchar(255)"`
+	// 分词编码，为空时按模型名称推断
+	Tokenizer string `json:"tokenizer" gorm:"type:varchar(32)"`
+
+	// 能力标记，只对chat类型的模型有效，转发请求前按此校验
+	SupportsVision    bool `json:"supports_vision" gorm:"not null;default:false"`
+	SupportsTools     bool `json:"supports_tools" gorm:"not null;default:false"`
+	SupportsJSONMode  bool `json:"supports_json_mode" gorm:"not null;default:false"`
+	SupportsStreaming bool `json:"supports_streaming" gorm:"not null;default:false"`
+
+	// 重排序分数的归一化方式，只对rerank类型的模型有效
+	ScoreNormalization string `json:"score_normalization" gorm:"type:varchar(32);not null;default:''"`
+
+	// 健康检查状态，单独保存在t_model_status表
+	Status *ModelStatus `json:"status,omitempty" gorm:"-"`
+
+	// 停用或删除的模型保留配置，但不再转发请求；删除为软删除，可以恢复。
+	// 不声明gorm默认值，否则插入Enabled为false的模型时gorm会省略该字段而使用数据库默认值
+	Enabled   bool           `json:"enabled" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// 乐观锁版本号，每次变更加1，对应响应头ETag
+	Version int `json:"version" gorm:"not null;default:1"`
+}
+
+// 模型健康状态，ModelStatusUnknown只用于查询未探测过的模型
+const (
+	ModelStatusHealthy  = "healthy"
+	ModelStatusDegraded = "degraded"
+	ModelStatusDown     = "down"
+	ModelStatusUnknown  = "unknown"
+)
+
+// ModelStatus 模型的健康检查状态，LatencyMS为最近探测耗时的滑动平均
+type ModelStatus struct {
+	ModelID             string     `json:"model_id" gorm:"primaryKey;type:varchar(64)"`
+	Status              string     `json:"status" gorm:"type:varchar(16);not null"`
+	LastCheckAt         *time.Time `json:"last_check_at"`
+	LastError           string     `json:"last_error" gorm:"type:text"`
+	LatencyMS           int64      `json:"latency_ms"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// 模型类型
+const (
+	ModelTypeChat       = "chat"
+	ModelTypeEmbedding  = "embedding"
+	ModelTypeRerank     = "rerank"
+	ModelTypeImage      = "image"
+	ModelTypeAudio      = "audio"
+	ModelTypeModeration = "moderation"
+)
+
+// ModelTypes 返回支持的模型类型
+func ModelTypes() []string {
+	return []string{ModelTypeChat, ModelTypeEmbedding, ModelTypeRerank, ModelTypeImage, ModelTypeAudio, ModelTypeModeration}
+}
+
+// IsValidModelType 判断模型类型是否合法
+func IsValidModelType(modelType string) bool {
+	return slices.Contains(ModelTypes(), modelType)
+}
+
+// IsType 判断模型是否为指定类型，兼容大小写不一致的历史数据
+func (m *Model) IsType(modelType string) bool {
+	return strings.EqualFold(m.Type, modelType)
+}
+
+// HasCapabilities 是否设置了任意能力标记
+func (m *Model) HasCapabilities() bool {
+	return m.SupportsVision || m.SupportsTools || m.SupportsJSONMode || m.SupportsStreaming
+}
+
+// 消息超出上下文窗口时的处理策略
+const (
+	ContextStrategyNone      = ""          // 直接拒绝请求
+	ContextStrategyTruncate  = "truncate"  // 保留系统提示词，丢弃最早的消息
+	ContextStrategySummarize = "summarize" // 使用摘要模型总结较早的消息
+)
+
+// IsValidContextStrategy 判断上下文策略是否合法
+func IsValidContextStrategy(strategy string) bool {
+	switch strategy {
+	case ContextStrategyNone, ContextStrategyTruncate, ContextStrategySummarize:
+		return true
+	}
+	return false
+}
+
+// 重排序模型的分数归一化方式，按上游接口的约定配置，不根据返回的分数推断
+const (
+	ScoreNormalizationNone    = ""        // 上游返回的分数已在0~1之间（Cohere、Jina），原样返回
+	ScoreNormalizationSigmoid = "sigmoid" // 上游返回原始logit（如TEI开启raw_scores），按sigmoid映射到0~1
+)
+
+// IsValidScoreNormalization 判断分数归一化方式是否合法
+func IsValidScoreNormalization(normalization string) bool {
+	switch normalization {
+	case ScoreNormalizationNone, ScoreNormalizationSigmoid:
+		return true
+	}
+	return false
 }
 
 // CreateModelRequest 创建模型的请求结构
@@ -24,7 +139,21 @@ type CreateModelRequest struct {
 	APIKey     string `json:"api_key" binding:"required"`
 	Timeout    int    `json:"timeout" binding:"required"`
 	Type       string `json:"type" binding:"required"`
-	Dimensions int    `json:"dimensions" binding:"required"`
+	Dimensions int    `json:"dimensions" binding:"gte=0"`
+
+	ContextWindow     int    `json:"context_window" binding:"gte=0"`
+	MaxOutputTokens   int    `json:"max_output_tokens" binding:"gte=0"`
+	ContextStrategy   string `json:"context_strategy"`
+	SummarizerModelID string `json:"summarizer_model_id"`
+	SummarizerModel   string `json:"summarizer_model"`
+	Tokenizer         string `json:"tokenizer"`
+
+	SupportsVision    bool `json:"supports_vision"`
+	SupportsTools     bool `json:"supports_tools"`
+	SupportsJSONMode  bool `json:"supports_json_mode"`
+	SupportsStreaming bool `json:"supports_streaming"`
+
+	ScoreNormalization string `json:"score_normalization"`
 }
 
 // UpdateModelRequest 更新模型的请求结构
@@ -34,7 +163,56 @@ type UpdateModelRequest struct {
 	APIKey     *string `json:"api_key"`
 	Timeout    *int    `json:"timeout"`
 	Type       *string `json:"type"`
-	Dimensions *int    `json:"dimensions"`
+	Dimensions *int    `json:"dimensions" binding:"omitempty,gte=0"`
+
+	ContextWindow     *int    `json:"context_window" binding:"omitempty,gte=0"`
+	MaxOutputTokens   *int    `json:"max_output_tokens" binding:"omitempty,gte=0"`
+	ContextStrategy   *string `json:"context_strategy"`
+	SummarizerModelID *string `json:"summarizer_model_id"`
+	SummarizerModel   *string `json:"summarizer_model"`
+	Tokenizer         *string `json:"tokenizer"`
+
+	SupportsVision    *bool `json:"supports_vision"`
+	SupportsTools     *bool `json:"supports_tools"`
+	SupportsJSONMode  *bool `json:"supports_json_mode"`
+	SupportsStreaming *bool `json:"supports_streaming"`
+
+	ScoreNormalization *string `json:"score_normalization"`
+}
+
+// ModelListQuery 查询模型列表的参数；Cursor不为空时按游标分页，忽略Page
+type ModelListQuery struct {
+	Type           string `form:"type"`
+	Name           string `form:"name"` // 名称包含的子串
+	Enabled        *bool  `form:"enabled"`
+	Status         string `form:"status"` // 健康状态，unknown表示未探测
+	IncludeDeleted bool   `form:"include_deleted"`
+	Sort           string `form:"sort"`  // name、created_at或updated_at，默认created_at
+	Order          string `form:"order"` // asc或desc，默认asc
+	Page           int    `form:"page"`
+	PageSize       int    `form:"page_size"`
+	Cursor         string `form:"cursor"`
+}
+
+// ModelTestRequest 模型连通性测试的请求结构，Model为上游模型名，为空时使用模型名称
+type ModelTestRequest struct {
+	Model string `json:"model"`
+}
+
+// ModelTestResult 模型连通性测试结果
+type ModelTestResult struct {
+	Success       bool   `json:"success"`
+	Reachable     bool   `json:"reachable"`      // 是否收到上游的HTTP响应
+	Authorized    bool   `json:"authorized"`     // API Key 是否被上游接受
+	StatusCode    int    `json:"status_code"`    // 上游返回的HTTP状态码
+	LatencyMS     int64  `json:"latency_ms"`     // 请求耗时，单位毫秒
+	ReturnedModel string `json:"returned_model"` // 上游响应中的模型名
+	Error         string `json:"error,omitempty"`
+
+	// 以下字段只对embedding类型的模型有效
+	Dimensions         int   `json:"dimensions,omitempty"`          // 上游实际返回的向量维度
+	ExpectedDimensions int   `json:"expected_dimensions,omitempty"` // 模型配置的向量维度
+	DimensionsMatch    *bool `json:"dimensions_match,omitempty"`
 }
 
 // ChatMessage OpenAI风格的对话消息结构体
@@ -47,9 +225,120 @@ type ChatMessage struct {
 type ChatRequest struct {
 	Model    string        `json:"model" binding:"required"`
 	Messages []ChatMessage `json:"messages" binding:"required"`
+
+	// 以下字段原样转发给上游，转发前按模型的能力标记校验
+	Stream         bool            `json:"stream,omitempty"`
+	Tools          json.RawMessage `json:"tools,omitempty"`
+	ToolChoice     json.RawMessage `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat OpenAI风格的输出格式，Type为text、json_object或json_schema
+type ResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+// ChatImageURL 图文消息中的图片地址，可以是 http(s) 地址或 data URL
+type ChatImageURL struct {
+	URL string `json:"url"`
+}
+
+// ChatContentPart OpenAI风格的图文消息内容片段，Type为text或image_url
+type ChatContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ChatImageURL `json:"image_url,omitempty"`
+}
+
+// VisionChatMessage OpenAI风格的图文消息
+type VisionChatMessage struct {
+	Role    string            `json:"role"`
+	Content []ChatContentPart `json:"content"`
+}
+
+// VisionChatRequest OpenAI风格的图文对话请求结构体
+type VisionChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []VisionChatMessage `json:"messages"`
+	MaxTokens int                 `json:"max_tokens,omitempty"`
+}
+
+// ChatUsage OpenAI风格的token用量
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatChoice OpenAI风格的回复候选
+type ChatChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// ChatResponse OpenAI风格的对话响应结构体
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   ChatUsage    `json:"usage"`
+}
+
+// TokenizeResponse token计数接口的响应结构
+type TokenizeResponse struct {
+	Encoding        string `json:"encoding"`
+	PromptTokens    int    `json:"prompt_tokens"`
+	MessageTokens   []int  `json:"message_tokens"`
+	ContextWindow   int    `json:"context_window"`
+	MaxOutputTokens int    `json:"max_output_tokens"`
+	// RemainingTokens 上下文窗口扣除输入和预留输出后剩余的token数，未配置上下文窗口时为0
+	RemainingTokens int `json:"remaining_tokens"`
 }
 
 // TableName 指定表名
 func (Model) TableName() string {
 	return "t_model"
 }
+
+// ModelLoader 返回按model_id查询未删除模型的函数，模型不存在时返回nil，用于加载摘要模型
+func ModelLoader(database *gorm.DB) func(modelID string) (*Model, error) {
+	return func(modelID string) (*Model, error) {
+		var model Model
+		if err := database.Where("model_id = ?", modelID).First(&model).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &model, nil
+	}
+}
+
+// TableName 指定表名
+func (ModelStatus) TableName() string {
+	return "t_model_status"
+}
+
+// EmbeddingRequest OpenAI风格的向量化请求结构体
+type EmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// EmbeddingData 单条输入的向量
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingResponse OpenAI风格的向量化响应结构体
+type EmbeddingResponse struct {
+	Model string          `json:"model"`
+	Data  []EmbeddingData `json:"data"`
+	Usage ChatUsage       `json:"usage"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// ModelExportVersion 导出文件的格式版本，版本2起加密API Key使用scrypt加盐派生的密钥
+const ModelExportVersion = 2
+
+// 导出时密钥的处理方式
+const (
+	SecretsOmit    = "omit"    // 不导出API Key
+	SecretsPlain   = "plain"   // 明文导出
+	SecretsEncrypt = "encrypt" // 使用口令加密后导出
+)
+
+// 导入时每个模型的处理结果
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionDisable   = "disable" // 声明式同步时停用未列出的模型
+)
+
+// ModelExport 模型注册表的导出文件，可以直接用于导入
+type ModelExport struct {
+	Version    int         `json:"version" yaml:"version"`
+	ExportedAt time.Time   `json:"exported_at" yaml:"exported_at"`
+	Secrets    string      `json:"secrets" yaml:"secrets"`
+	Models     []ModelSpec `json:"models" yaml:"models"`
+}
+
+// ModelSpec 导入导出的模型配置，按名称匹配，不包含model_id；摘要模型用名称引用
+type ModelSpec struct {
+	Name            string `json:"name" yaml:"name"`
+	Endpoint        string `json:"endpoint" yaml:"endpoint"`
+	APIKey          string `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	APIKeyEncrypted string `json:"api_key_encrypted,omitempty" yaml:"api_key_encrypted,omitempty"`
+	APIKeyEnv       string `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"` // 声明式同步时从该环境变量读取API Key
+	Timeout         int    `json:"timeout" yaml:"timeout"`
+	Type            string `json:"type" yaml:"type"`
+	Dimensions      int    `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+
+	ContextWindow   int    `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+	MaxOutputTokens int    `json:"max_output_tokens,omitempty" yaml:"max_output_tokens,omitempty"`
+	ContextStrategy string `json:"context_strategy,omitempty" yaml:"context_strategy,omitempty"`
+	Summarizer      string `json:"summarizer,omitempty" yaml:"summarizer,omitempty"`
+	SummarizerModel string `json:"summarizer_model,omitempty" yaml:"summarizer_model,omitempty"`
+	Tokenizer       string `json:"tokenizer,omitempty" yaml:"tokenizer,omitempty"`
+
+	SupportsVision    bool `json:"supports_vision,omitempty" yaml:"supports_vision,omitempty"`
+	SupportsTools     bool `json:"supports_tools,omitempty" yaml:"supports_tools,omitempty"`
+	SupportsJSONMode  bool `json:"supports_json_mode,omitempty" yaml:"supports_json_mode,omitempty"`
+	SupportsStreaming bool `json:"supports_streaming,omitempty" yaml:"supports_streaming,omitempty"`
+
+	ScoreNormalization string `json:"score_normalization,omitempty" yaml:"score_normalization,omitempty"`
+
+	// 为空时新建的模型默认启用，已有模型保持原状态
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+}
+
+// ModelSyncFile 声明式同步的模型文件，API Key只能通过api_key_env引用环境变量
+type ModelSyncFile struct {
+	// 停用数据库中存在但文件中没有列出的模型
+	DisableUnlisted bool        `json:"disable_unlisted" yaml:"disable_unlisted"`
+	Models          []ModelSpec `json:"models" yaml:"models"`
+}
+
+// ModelImportItem 导入时单个模型的变更计划
+type ModelImportItem struct {
+	Name    string        `json:"name"`
+	ModelID string        `json:"model_id"`
+	Action  string        `json:"action"`
+	Diff    []FieldChange `json:"diff,omitempty"`
+}
+
+// ModelImportResult 导入结果，DryRun为true时没有写入数据库
+type ModelImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Disabled  int               `json:"disabled"`
+	Items     []ModelImportItem `json:"items"`
+}
+
+// NewModelSpec 提取模型的导入导出配置，summarizer为摘要模型的名称，API Key由调用方按需填写
+func NewModelSpec(m *Model, summarizer string) ModelSpec {
+	enabled := m.Enabled
+	return ModelSpec{
+		Name:       m.Name,
+		Endpoint:   m.Endpoint,
+		Timeout:    m.Timeout,
+		Type:       m.Type,
+		Dimensions: m.Dimensions,
+
+		ContextWindow:   m.ContextWindow,
+		MaxOutputTokens: m.MaxOutputTokens,
+		ContextStrategy: m.ContextStrategy,
+		Summarizer:      summarizer,
+		SummarizerModel: m.SummarizerModel,
+		Tokenizer:       m.Tokenizer,
+
+		SupportsVision:    m.SupportsVision,
+		SupportsTools:     m.SupportsTools,
+		SupportsJSONMode:  m.SupportsJSONMode,
+		SupportsStreaming: m.SupportsStreaming,
+
+		ScoreNormalization: m.ScoreNormalization,
+
+		Enabled: &enabled,
+	}
+}
+
+// ApplyTo 将配置写入模型；API Key和摘要模型ID由调用方解析后设置，Enabled为空时不修改
+func (s *ModelSpec) ApplyTo(m *Model) {
+	m.Name = s.Name
+	m.Endpoint = s.Endpoint
+	m.Timeout = s.Timeout
+	m.Type = s.Type
+	m.Dimensions = s.Dimensions
+
+	m.ContextWindow = s.ContextWindow
+	m.MaxOutputTokens = s.MaxOutputTokens
+	m.ContextStrategy = s.ContextStrategy
+	m.SummarizerModel = s.SummarizerModel
+	m.Tokenizer = s.Tokenizer
+
+	m.SupportsVision = s.SupportsVision
+	m.SupportsTools = s.SupportsTools
+	m.SupportsJSONMode = s.SupportsJSONMode
+	m.SupportsStreaming = s.SupportsStreaming
+
+	m.ScoreNormalization = s.ScoreNormalization
+
+	if s.Enabled != nil {
+		m.Enabled = *s.Enabled
+	}
+}
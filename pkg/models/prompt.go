@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+)
+
+// 模板变量支持的类型
+const (
+	PromptVariableString  = "string"
+	PromptVariableNumber  = "number"
+	PromptVariableBoolean = "boolean"
+)
+
+// PromptVariable 提示词模板中声明的变量
+type PromptVariable struct {
+	Name        string `json:"name" binding:"required"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PromptTemplate 提示词模板的一个版本，同名模板的每次修改都会新增一个版本
+type PromptTemplate struct {
+	TemplateID  string           `json:"template_id" gorm:"primaryKey;type:varchar(64)"`
+	Name        string           `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_prompt_name_version"`
+	Version     int              `json:"version" gorm:"not null;uniqueIndex:idx_prompt_name_version"`
+	Active      bool             `json:"active" gorm:"not null;default:false"`
+	Description string           `json:"description" gorm:"type:varchar(1024)"`
+	Body        string           `json:"body" gorm:"type:text;not null"`
+	Variables   []PromptVariable `json:"variables" gorm:"type:text;serializer:json"`
+	ModelID     string           `json:"model_id" gorm:"type:varchar(64)"`
+	Model       string           `json:"model" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CreatePromptTemplateRequest 创建提示词模板的请求结构
+type CreatePromptTemplateRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Description string           `json:"description"`
+	Body        string           `json:"body" binding:"required"`
+	Variables   []PromptVariable `json:"variables" binding:"dive"`
+	ModelID     string           `json:"model_id"`
+	Model       string           `json:"model"`
+}
+
+// UpdatePromptTemplateRequest 更新提示词模板的请求结构，未提供的字段沿用当前版本
+type UpdatePromptTemplateRequest struct {
+	Description *string           `json:"description"`
+	Body        *string           `json:"body"`
+	Variables   *[]PromptVariable `json:"variables"`
+	ModelID     *string           `json:"model_id"`
+	Model       *string           `json:"model"`
+}
+
+// RollbackPromptTemplateRequest 回滚提示词模板的请求结构
+type RollbackPromptTemplateRequest struct {
+	Version int `json:"version" binding:"required,gt=0"`
+}
+
+// RenderPromptRequest 渲染提示词模板的请求结构，Version为0时使用当前生效的版本
+type RenderPromptRequest struct {
+	Version   int            `json:"version" binding:"gte=0"`
+	Variables map[string]any `json:"variables"`
+}
+
+// PromptChatRequest 使用提示词模板发起对话的请求结构
+// 渲染结果作为系统提示词，Messages追加在其后；ModelID和Model为空时使用模板绑定的模型
+type PromptChatRequest struct {
+	RenderPromptRequest
+	ModelID  string        `json:"model_id"`
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages" binding:"dive"`
+}
+
+// TableName 指定表名
+func (PromptTemplate) TableName() string {
+	return "t_prompt_template"
+}
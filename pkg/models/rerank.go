@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// RerankDocument 待排序的文档，兼容字符串和 {"text": "..."} 两种写法
+type RerankDocument struct {
+	Text string `json:"text"`
+}
+
+// UnmarshalJSON 支持直接传入字符串
+func (d *RerankDocument) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		d.Text = text
+		return nil
+	}
+	type document RerankDocument
+	return json.Unmarshal(data, (*document)(d))
+}
+
+// RerankRequest Cohere/Jina风格的重排序请求，Model为注册的rerank模型名称或模型ID
+type RerankRequest struct {
+	Model           string           `json:"model" binding:"required"`
+	Query           string           `json:"query" binding:"required"`
+	Documents       []RerankDocument `json:"documents" binding:"required,min=1"`
+	TopN            int              `json:"top_n" binding:"gte=0"`
+	ReturnDocuments bool             `json:"return_documents"`
+}
+
+// RerankResult 单个文档的排序结果，Index为文档在请求中的序号，RelevanceScore归一化到0~1
+type RerankResult struct {
+	Index          int             `json:"index"`
+	RelevanceScore float64         `json:"relevance_score"`
+	Document       *RerankDocument `json:"document,omitempty"`
+}
+
+// RerankUsage 重排序消耗的token数，上游没有返回时为0
+type RerankUsage struct {
+	TotalTokens int `json:"total_tokens"`
+}
+
+// RerankResponse Cohere/Jina风格的重排序响应，结果按相关性降序排列
+type RerankResponse struct {
+	Model   string         `json:"model"`
+	Results []RerankResult `json:"results"`
+	Usage   RerankUsage    `json:"usage"`
+}
+
+// UpstreamRerankRequest 发送给上游重排序接口的请求
+type UpstreamRerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
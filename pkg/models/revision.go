@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 模型配置变更的操作类型
+const (
+	RevisionActionCreate   = "create"
+	RevisionActionUpdate   = "update"
+	RevisionActionDelete   = "delete"
+	RevisionActionRestore  = "restore"
+	RevisionActionPurge    = "purge"
+	RevisionActionEnable   = "enable"
+	RevisionActionDisable  = "disable"
+	RevisionActionRollback = "rollback"
+)
+
+// RedactedValue 脱敏后的密钥
+const RedactedValue = "******"
+
+// ModelRevision 模型配置的一次变更记录，写入后不再修改；Snapshot为变更后的配置，Diff为变更的字段，均不包含密钥明文
+type ModelRevision struct {
+	RevisionID string        `json:"revision_id" gorm:"primaryKey;type:varchar(64)"`
+	ModelID    string        `json:"model_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_model_revision"`
+	Revision   int           `json:"revision" gorm:"not null;uniqueIndex:idx_model_revision"`
+	Action     string        `json:"action" gorm:"type:varchar(32);not null"`
+	Actor      string        `json:"actor" gorm:"type:varchar(255)"`
+	Snapshot   ModelSnapshot `json:"snapshot" gorm:"type:json;serializer:json"`
+	Diff       []FieldChange `json:"diff" gorm:"type:json;serializer:json"`
+	CreatedAt  time.Time     `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ModelSnapshot 修订记录中保存的模型配置，不包含API Key
+type ModelSnapshot struct {
+	Name       string `json:"name"`
+	Endpoint   string `json:"endpoint"`
+	Timeout    int    `json:"timeout"`
+	Type       string `json:"type"`
+	Dimensions int    `json:"dimensions"`
+
+	ContextWindow     int    `json:"context_window"`
+	MaxOutputTokens   int    `json:"max_output_tokens"`
+	ContextStrategy   string `json:"context_strategy"`
+	SummarizerModelID string `json:"summarizer_model_id"`
+	SummarizerModel   string `json:"summarizer_model"`
+	Tokenizer         string `json:"tokenizer"`
+
+	SupportsVision    bool `json:"supports_vision"`
+	SupportsTools     bool `json:"supports_tools"`
+	SupportsJSONMode  bool `json:"supports_json_mode"`
+	SupportsStreaming bool `json:"supports_streaming"`
+
+	ScoreNormalization string `json:"score_normalization"`
+
+	Enabled bool `json:"enabled"`
+	Deleted bool `json:"deleted"`
+}
+
+// FieldChange 修订记录中一个字段的变化，密钥字段的值为脱敏后的占位符
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// RollbackModelRequest 回滚模型配置的请求结构
+type RollbackModelRequest struct {
+	Revision int `json:"revision" binding:"required,gt=0"`
+}
+
+// NewModelSnapshot 提取模型的配置快照
+func NewModelSnapshot(m *Model) ModelSnapshot {
+	return ModelSnapshot{
+		Name:       m.Name,
+		Endpoint:   m.Endpoint,
+		Timeout:    m.Timeout,
+		Type:       m.Type,
+		Dimensions: m.Dimensions,
+
+		ContextWindow:     m.ContextWindow,
+		MaxOutputTokens:   m.MaxOutputTokens,
+		ContextStrategy:   m.ContextStrategy,
+		SummarizerModelID: m.SummarizerModelID,
+		SummarizerModel:   m.SummarizerModel,
+		Tokenizer:         m.Tokenizer,
+
+		SupportsVision:    m.SupportsVision,
+		SupportsTools:     m.SupportsTools,
+		SupportsJSONMode:  m.SupportsJSONMode,
+		SupportsStreaming: m.SupportsStreaming,
+
+		ScoreNormalization: m.ScoreNormalization,
+
+		Enabled: m.Enabled,
+		Deleted: m.DeletedAt.Valid,
+	}
+}
+
+// ApplyTo 将快照中的配置写回模型；API Key、启用和删除状态不随快照回滚
+func (s ModelSnapshot) ApplyTo(m *Model) {
+	m.Name = s.Name
+	m.Endpoint = s.Endpoint
+	m.Timeout = s.Timeout
+	m.Type = s.Type
+	m.Dimensions = s.Dimensions
+
+	m.ContextWindow = s.ContextWindow
+	m.MaxOutputTokens = s.MaxOutputTokens
+	m.ContextStrategy = s.ContextStrategy
+	m.SummarizerModelID = s.SummarizerModelID
+	m.SummarizerModel = s.SummarizerModel
+	m.Tokenizer = s.Tokenizer
+
+	m.SupportsVision = s.SupportsVision
+	m.SupportsTools = s.SupportsTools
+	m.SupportsJSONMode = s.SupportsJSONMode
+	m.SupportsStreaming = s.SupportsStreaming
+
+	m.ScoreNormalization = s.ScoreNormalization
+}
+
+// DiffModels 比较变更前后的模型配置，before为nil表示新建；API Key只记录是否变化
+func DiffModels(before, after *Model) []FieldChange {
+	var oldFields map[string]any
+	if before != nil {
+		oldFields = snapshotFields(NewModelSnapshot(before))
+	}
+	newFields := snapshotFields(NewModelSnapshot(after))
+
+	keys := make([]string, 0, len(newFields))
+	for k := range newFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := make([]FieldChange, 0)
+	for _, k := range keys {
+		old, ok := oldFields[k]
+		if ok && reflect.DeepEqual(old, newFields[k]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: k, Old: old, New: newFields[k]})
+	}
+	if before == nil || before.APIKey != after.APIKey {
+		change := FieldChange{Field: "api_key", New: RedactedValue}
+		if before != nil {
+			change.Old = RedactedValue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func snapshotFields(s ModelSnapshot) map[string]any {
+	data, _ := json.Marshal(s)
+	var fields map[string]any
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// TableName 指定表名
+func (ModelRevision) TableName() string {
+	return "t_model_revision"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// VectorCollection MySQL向量存储中的集合
+type VectorCollection struct {
+	Name       string    `json:"name" gorm:"primaryKey;type:varchar(255)"`
+	Dimensions int       `json:"dimensions" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// VectorRecord MySQL向量存储中的一条记录，向量按小端float32编码保存
+type VectorRecord struct {
+	Collection string         `json:"collection" gorm:"primaryKey;type:varchar(255)"`
+	RecordID   string         `json:"record_id" gorm:"primaryKey;type:varchar(512)"`
+	Content    string         `json:"content" gorm:"type:longtext"`
+	Metadata   map[string]any `json:"metadata" gorm:"type:json;serializer:json"`
+	Vector     []byte         `json:"-" gorm:"type:mediumblob;not null"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (VectorCollection) TableName() string {
+	return "t_vector_collection"
+}
+
+// TableName 指定表名
+func (VectorRecord) TableName() string {
+	return "t_vector_record"
+}
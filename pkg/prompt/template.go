@@ -0,0 +1,118 @@
+package prompt
+
+import (
+	"bytes"
+	"regexp"
+	"text/template"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+)
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parse 解析模板内容，引用未提供的变量时渲染报错
+func Parse(name, body string) (*template.Template, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "模板语法错误")
+	}
+	return tpl, nil
+}
+
+// ValidateVariables 校验模板声明的变量，变量类型为空时视为 string
+func ValidateVariables(variables []models.PromptVariable) error {
+	seen := make(map[string]struct{}, len(variables))
+	for i := range variables {
+		v := &variables[i]
+		if !variableNamePattern.MatchString(v.Name) {
+			return errors.Errorf("变量名不合法: %s", v.Name)
+		}
+		if _, ok := seen[v.Name]; ok {
+			return errors.Errorf("变量重复声明: %s", v.Name)
+		}
+		seen[v.Name] = struct{}{}
+		if v.Type == "" {
+			v.Type = models.PromptVariableString
+		}
+		switch v.Type {
+		case models.PromptVariableString, models.PromptVariableNumber, models.PromptVariableBoolean:
+		default:
+			return errors.Errorf("变量%s的类型不支持: %s", v.Name, v.Type)
+		}
+		if v.Default != nil && !matchType(v.Type, v.Default) {
+			return errors.Errorf("变量%s的默认值类型应为%s", v.Name, v.Type)
+		}
+	}
+	return nil
+}
+
+// Render 按模板声明的变量校验取值、补全默认值并渲染模板
+func Render(pt *models.PromptTemplate, values map[string]any) (string, error) {
+	declared := make(map[string]models.PromptVariable, len(pt.Variables))
+	for _, v := range pt.Variables {
+		declared[v.Name] = v
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", errors.Errorf("未声明的变量: %s", name)
+		}
+	}
+
+	data := make(map[string]any, len(pt.Variables))
+	for _, v := range pt.Variables {
+		value, ok := values[v.Name]
+		if !ok || value == nil {
+			if v.Required {
+				return "", errors.Errorf("缺少必填变量: %s", v.Name)
+			}
+			value = v.Default
+			if value == nil {
+				value = zeroValue(v.Type)
+			}
+		}
+		if !matchType(v.Type, value) {
+			return "", errors.Errorf("变量%s的类型应为%s", v.Name, v.Type)
+		}
+		data[v.Name] = value
+	}
+
+	tpl, err := Parse(pt.Name, pt.Body)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "模板渲染失败")
+	}
+	return buf.String(), nil
+}
+
+func matchType(typ string, value any) bool {
+	switch typ {
+	case models.PromptVariableNumber:
+		switch value.(type) {
+		case float64, float32, int, int64, int32:
+			return true
+		}
+		return false
+	case models.PromptVariableBoolean:
+		_, ok := value.(bool)
+		return ok
+	default:
+		_, ok := value.(string)
+		return ok
+	}
+}
+
+func zeroValue(typ string) any {
+	switch typ {
+	case models.PromptVariableNumber:
+		return float64(0)
+	case models.PromptVariableBoolean:
+		return false
+	default:
+		return ""
+	}
+}
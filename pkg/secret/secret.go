@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrDecrypt 密文不合法或口令错误
+var ErrDecrypt = errors.New("解密失败，密文不合法或口令错误")
+
+// scrypt参数，派生一次密钥约需几十毫秒，同一个盐只派生一次
+const (
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keySize  = 32
+)
+
+// newGCM 使用scrypt由口令和盐派生AES-256密钥
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "派生密钥失败")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypter 使用随机盐派生密钥的AES-GCM加密器，同一个导出文件共用一个盐，只派生一次密钥
+type Encrypter struct {
+	salt []byte
+	gcm  cipher.AEAD
+}
+
+// NewEncrypter 生成随机盐并由口令派生密钥
+func NewEncrypter(passphrase string) (*Encrypter, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "生成随机盐失败")
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{salt: salt, gcm: gcm}, nil
+}
+
+// Encrypt 加密明文，返回base64编码的盐、nonce和密文
+func (e *Encrypter) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, e.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "生成随机数失败")
+	}
+	sealed := append([]byte{}, e.salt...)
+	sealed = append(sealed, nonce...)
+	sealed = e.gcm.Seal(sealed, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypter 解密Encrypter生成的密文，按盐缓存派生的密钥
+type Decrypter struct {
+	passphrase string
+	gcms       map[string]cipher.AEAD
+}
+
+// NewDecrypter 创建使用指定口令的解密器
+func NewDecrypter(passphrase string) *Decrypter {
+	return &Decrypter{passphrase: passphrase, gcms: make(map[string]cipher.AEAD)}
+}
+
+// Decrypt 解密Encrypter.Encrypt生成的密文
+func (d *Decrypter) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < saltSize {
+		return "", ErrDecrypt
+	}
+	salt := string(data[:saltSize])
+	gcm, ok := d.gcms[salt]
+	if !ok {
+		if gcm, err = newGCM(d.passphrase, data[:saltSize]); err != nil {
+			return "", err
+		}
+		d.gcms[salt] = gcm
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return "", ErrDecrypt
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
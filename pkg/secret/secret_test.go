@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	enc, err := NewEncrypter("pass")
+	if err != nil {
+		t.Fatalf("创建加密器失败: %v", err)
+	}
+	a, err := enc.Encrypt("sk-a")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	b, _ := enc.Encrypt("sk-b")
+	other, _ := NewEncrypter("pass")
+	c, _ := other.Encrypt("sk-a")
+
+	// 同一个加密器共用盐，不同加密器的盐不同
+	rawA, _ := base64.StdEncoding.DecodeString(a)
+	rawB, _ := base64.StdEncoding.DecodeString(b)
+	rawC, _ := base64.StdEncoding.DecodeString(c)
+	if string(rawA[:saltSize]) != string(rawB[:saltSize]) {
+		t.Errorf("同一个加密器生成的密文使用了不同的盐")
+	}
+	if string(rawA[:saltSize]) == string(rawC[:saltSize]) {
+		t.Errorf("不同加密器生成的密文使用了相同的盐")
+	}
+
+	dec := NewDecrypter("pass")
+	for ciphertext, want := range map[string]string{a: "sk-a", b: "sk-b", c: "sk-a"} {
+		if plain, err := dec.Decrypt(ciphertext); err != nil || plain != want {
+			t.Errorf("解密结果为%q(%v)，期望%q", plain, err, want)
+		}
+	}
+	if len(dec.gcms) != 2 {
+		t.Errorf("解密器缓存了%d个密钥，期望每个盐只派生一次", len(dec.gcms))
+	}
+
+	if _, err := NewDecrypter("wrong").Decrypt(a); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("使用错误的口令解密返回%v，期望ErrDecrypt", err)
+	}
+	rawA[len(rawA)-1] ^= 1
+	if _, err := dec.Decrypt(base64.StdEncoding.EncodeToString(rawA)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("解密被篡改的密文返回%v，期望ErrDecrypt", err)
+	}
+	if _, err := dec.Decrypt("c2hvcnQ="); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("解密过短的密文返回%v，期望ErrDecrypt", err)
+	}
+	if _, err := NewEncrypter(""); err == nil {
+		t.Errorf("口令为空时创建加密器成功，期望失败")
+	}
+}
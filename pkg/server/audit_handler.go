@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// auditModelIDKey 处理器通过该键告知审计中间件操作的模型ID，用于创建模型等路由中没有模型ID的请求
+const auditModelIDKey = "audit_model_id"
+
+// 审计日志列表的分页大小
+const (
+	defaultAuditPageSize = 10
+	maxAuditPageSize     = 100
+)
+
+// auditActions 需要审计的路由对应的操作名称，键为请求方法和路由模板；新增变更路由时需要在此登记或加入auditSkipRoutes
+var auditActions = map[string]string{
+	"POST /api/v1/models/create":              "CreateModel",
+	"POST /api/v1/models/import":              "ImportModels",
+	"PUT /api/v1/models/:id":                  "UpdateModel",
+	"DELETE /api/v1/models/:id":               "DeleteModel",
+	"POST /api/v1/models/:id/disable":         "DisableModel",
+	"POST /api/v1/models/:id/enable":          "EnableModel",
+	"POST /api/v1/models/:id/restore":         "RestoreModel",
+	"DELETE /api/v1/models/:id/purge":         "PurgeModel",
+	"POST /api/v1/models/:id/rollback":        "RollbackModel",
+	"POST /api/v1/conversations/create":       "CreateConversation",
+	"DELETE /api/v1/conversations/:id":        "DeleteConversation",
+	"POST /api/v1/prompts/create":             "CreatePromptTemplate",
+	"PUT /api/v1/prompts/:name":               "UpdatePromptTemplate",
+	"POST /api/v1/prompts/:name/rollback":     "RollbackPromptTemplate",
+	"DELETE /api/v1/prompts/:name":            "DeletePromptTemplate",
+	"POST /api/v1/kb/create":                  "CreateKnowledgeBase",
+	"DELETE /api/v1/kb/:id":                   "DeleteKnowledgeBase",
+	"POST /api/v1/kb/:id/documents":           "AddDocument",
+	"DELETE /api/v1/kb/:id/documents/:doc_id": "DeleteDocument",
+}
+
+// auditSkipRoutes 不改变管理数据的POST路由，不记录审计事件
+var auditSkipRoutes = map[string]bool{
+	"/api/v1/models/chat/:id":            true,
+	"/api/v1/models/:id/tokenize":        true,
+	"/api/v1/models/:id/test":            true,
+	"/api/v1/conversations/:id/messages": true,
+	"/api/v1/prompts/:name/render":       true,
+	"/api/v1/prompts/:name/chat":         true,
+	"/api/v1/kb/:id/search":              true,
+	"/api/v1/kb/:id/query":               true,
+}
+
+// auditMiddleware 为请求分配请求ID，并在变更请求处理完成后记录审计事件
+func auditMiddleware(auditor *audit.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		c.Header("X-Request-ID", requestID)
+		if !isAuditedRequest(c) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		modelID := c.GetString(auditModelIDKey)
+		if modelID == "" && strings.HasPrefix(route, "/api/v1/models/") {
+			modelID = c.Param("id")
+		}
+		auditor.Record(context.Background(), &models.AuditLog{
+			AuditID:   uuid.New().String(),
+			RequestID: requestID,
+			Actor:     requestActor(c),
+			ClientIP:  c.ClientIP(),
+			Action:    auditAction(c.Request.Method, route),
+			Method:    c.Request.Method,
+			Route:     route,
+			Path:      c.Request.URL.Path,
+			ModelID:   modelID,
+			Status:    c.Writer.Status(),
+			LatencyMS: time.Since(start).Milliseconds(),
+			CreatedAt: start,
+		})
+	}
+}
+
+// isAuditedRequest 判断是否为需要审计的变更请求，未匹配路由的请求不记录
+func isAuditedRequest(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	route := c.FullPath()
+	return route != "" && !auditSkipRoutes[route]
+}
+
+// auditAction 返回路由对应的操作名称，未登记的路由以请求方法和路由模板作为操作名称并记录警告
+func auditAction(method, route string) string {
+	key := method + " " + route
+	if action, ok := auditActions[key]; ok {
+		return action
+	}
+	zap.S().Warnf("路由%s未登记审计操作名称", key)
+	return key
+}
+
+// AuditHandler 审计日志相关的处理器
+type AuditHandler struct{}
+
+// NewAuditHandler 创建新的审计日志处理器
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{}
+}
+
+// GetAuditLogs 查询审计日志，可按操作人、操作、模型ID和时间范围过滤，按时间倒序分页
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	var query models.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+	if !query.Start.IsZero() && !query.End.IsZero() && query.End.Before(query.Start) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: end 不能早于 start"))
+		return
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = defaultAuditPageSize
+	}
+	query.PageSize = min(query.PageSize, maxAuditPageSize)
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx).Model(&models.AuditLog{})
+	if query.Actor != "" {
+		database = database.Where("actor = ?", query.Actor)
+	}
+	if query.Action != "" {
+		database = database.Where("action = ?", query.Action)
+	}
+	if query.ModelID != "" {
+		database = database.Where("model_id = ?", query.ModelID)
+	}
+	if !query.Start.IsZero() {
+		database = database.Where("created_at >= ?", query.Start)
+	}
+	if !query.End.IsZero() {
+		database = database.Where("created_at < ?", query.End)
+	}
+
+	var total int64
+	if err := database.Count(&total).Error; err != nil {
+		zap.S().Errorf("查询审计日志总数失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询审计日志总数失败: "+err.Error()))
+		return
+	}
+	var logs []models.AuditLog
+	if err := database.Order("created_at DESC").Limit(query.PageSize).Offset((query.Page - 1) * query.PageSize).Find(&logs).Error; err != nil {
+		zap.S().Errorf("查询审计日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询审计日志失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"list":      logs,
+		"total":     total,
+		"page":      query.Page,
+		"page_size": query.PageSize,
+	}, "查询审计日志成功"))
+}
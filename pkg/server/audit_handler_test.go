@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/vectorstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAuditActionsCoverRoutes 每个变更路由都需要登记审计操作名称或明确跳过审计
+func TestAuditActionsCoverRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	engine := gin.New()
+	InitRouter(engine, vectorstore.NewMemoryStore(), auditor)
+
+	registered := make(map[string]bool)
+	for _, r := range engine.Routes() {
+		if !strings.HasPrefix(r.Path, "/api/v1/") {
+			continue
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			continue
+		}
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		_, hasAction := auditActions[key]
+		if hasAction == auditSkipRoutes[r.Path] {
+			t.Errorf("路由%s需要且只能在auditActions或auditSkipRoutes之一中登记", key)
+		}
+	}
+	for key := range auditActions {
+		if !registered[key] {
+			t.Errorf("auditActions中的路由%s不存在", key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"myapi/pkg/models"
+	"myapi/pkg/tokenizer"
+
+	"gorm.io/gorm"
+)
+
+// validateContextConfig 校验模型的上下文窗口和分词编码配置，返回错误提示，合法时返回空字符串
+func validateContextConfig(database *gorm.DB, model *models.Model) string {
+	if !models.IsValidContextStrategy(model.ContextStrategy) {
+		return fmt.Sprintf("不支持的上下文策略: %s", model.ContextStrategy)
+	}
+	if model.ContextWindow > 0 && model.MaxOutputTokens >= model.ContextWindow {
+		return "max_output_tokens 必须小于 context_window"
+	}
+	if model.Tokenizer != "" && !tokenizer.IsValidEncoding(model.Tokenizer) {
+		return fmt.Sprintf("不支持的分词编码: %s，可选值为 %s", model.Tokenizer, strings.Join(tokenizer.Encodings(), "|"))
+	}
+	if model.ContextStrategy == models.ContextStrategySummarize {
+		if model.SummarizerModelID == "" || model.SummarizerModel == "" {
+			return "summarize 策略需要指定 summarizer_model_id 和 summarizer_model"
+		}
+		var summarizer models.Model
+		if err := database.Where("model_id = ?", model.SummarizerModelID).First(&summarizer).Error; err != nil {
+			return "摘要模型不存在"
+		}
+		if !summarizer.IsType(models.ModelTypeChat) {
+			return "摘要模型必须是 chat 类型"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+	"myapi/pkg/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// conversationTitleLength 自动生成标题时截取的最大字符数
+const conversationTitleLength = 30
+
+// ConversationHandler 对话相关的处理器
+type ConversationHandler struct{}
+
+// NewConversationHandler 创建新的对话处理器
+func NewConversationHandler() *ConversationHandler {
+	return &ConversationHandler{}
+}
+
+// CreateConversation 创建对话
+func (h *ConversationHandler) CreateConversation(c *gin.Context) {
+	var req models.CreateConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("创建对话参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 检查绑定的模型是否存在
+	var model models.Model
+	if err := database.Where("model_id = ?", req.ModelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	conversation := models.Conversation{
+		ConversationID: uuid.New().String(),
+		Title:          req.Title,
+		ModelID:        req.ModelID,
+		Model:          req.Model,
+		SystemPrompt:   req.SystemPrompt,
+	}
+	if err := database.Create(&conversation).Error; err != nil {
+		zap.S().Errorf("创建对话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "创建对话失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功创建对话: %s, 模型ID: %s", conversation.ConversationID, conversation.ModelID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(conversation, "成功创建对话"))
+}
+
+// GetConversations 获取对话列表
+func (h *ConversationHandler) GetConversations(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	query := database.Model(&models.Conversation{})
+	if modelID := c.Query("model_id"); modelID != "" {
+		query = query.Where("model_id = ?", modelID)
+	}
+
+	var conversationList []models.Conversation
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		zap.S().Errorf("查询对话总数失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询对话总数失败: "+err.Error()))
+		return
+	}
+	if err := query.Order("updated_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&conversationList).Error; err != nil {
+		zap.S().Errorf("查询对话列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询对话列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"list":      conversationList,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "查询对话列表成功"))
+}
+
+// GetConversation 获取单个对话及其消息
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	conversation, ok := findConversation(c, database, conversationID)
+	if !ok {
+		return
+	}
+
+	var messageList []models.Message
+	if err := database.Where("conversation_id = ?", conversationID).Order("created_at ASC").Find(&messageList).Error; err != nil {
+		zap.S().Errorf("查询对话消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询对话消息失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"conversation": conversation,
+		"messages":     messageList,
+	}, "查询成功"))
+}
+
+// DeleteConversation 删除对话及其消息
+func (h *ConversationHandler) DeleteConversation(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	conversation, ok := findConversation(c, database, conversationID)
+	if !ok {
+		return
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", conversationID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&conversation).Error
+	})
+	if err != nil {
+		zap.S().Errorf("删除对话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除对话失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功删除对话: %s", conversationID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(conversation, "成功删除对话"))
+}
+
+// SendMessage 向对话追加用户消息，调用绑定的模型并保存回复
+func (h *ConversationHandler) SendMessage(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	conversation, ok := findConversation(c, database, conversationID)
+	if !ok {
+		return
+	}
+
+	var req models.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("发送消息参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	model, ok := findCallableModel(c, database.Where("model_id = ?", conversation.ModelID))
+	if !ok {
+		return
+	}
+
+	var history []models.Message
+	if err := database.Where("conversation_id = ?", conversationID).Order("created_at ASC").Find(&history).Error; err != nil {
+		zap.S().Errorf("查询对话消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询对话消息失败: "+err.Error()))
+		return
+	}
+
+	userMessage := models.Message{
+		MessageID:      uuid.New().String(),
+		ConversationID: conversationID,
+		Role:           "user",
+		Content:        req.Content,
+		CreatedAt:      time.Now(),
+	}
+
+	// 组装历史消息
+	chatReq := models.ChatRequest{Model: conversation.Model}
+	if conversation.SystemPrompt != "" {
+		chatReq.Messages = append(chatReq.Messages, models.ChatMessage{Role: "system", Content: conversation.SystemPrompt})
+	}
+	for _, m := range history {
+		chatReq.Messages = append(chatReq.Messages, models.ChatMessage{Role: m.Role, Content: m.Content})
+	}
+	chatReq.Messages = append(chatReq.Messages, models.ChatMessage{Role: userMessage.Role, Content: userMessage.Content})
+
+	// 按模型的上下文策略处理过长的历史
+	messages, err := llm.FitContext(ctx, &model, chatReq.Model, chatReq.Messages, models.ModelLoader(database))
+	if err != nil {
+		var tooLong *llm.ContextTooLongError
+		if errors.As(err, &tooLong) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		} else {
+			zap.S().Errorf("对话[%s]处理上下文失败: %v", conversationID, err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "处理上下文失败: "+err.Error()))
+		}
+		return
+	}
+	chatReq.Messages = messages
+
+	chatResp, err := llm.Chat(ctx, &model, &chatReq)
+	if err != nil {
+		if status, ok := llm.RejectionStatus(err); ok {
+			c.JSON(status, models.NewErrorResponse(status, err.Error()))
+			return
+		}
+		zap.S().Errorf("对话[%s]调用模型失败: %v", conversationID, err)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(502, err.Error()))
+		return
+	}
+
+	reply := chatResp.Choices[0].Message
+	assistantMessage := models.Message{
+		MessageID:        uuid.New().String(),
+		ConversationID:   conversationID,
+		Role:             "assistant",
+		Content:          reply.Content,
+		PromptTokens:     chatResp.Usage.PromptTokens,
+		CompletionTokens: chatResp.Usage.CompletionTokens,
+		TotalTokens:      chatResp.Usage.TotalTokens,
+	}
+
+	// 模型调用成功后再一并写入本轮的用户消息和回复，避免留下没有回复的用户消息
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&userMessage).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&assistantMessage).Error; err != nil {
+			return err
+		}
+		if conversation.Title == "" {
+			conversation.Title = util.TruncateRunes(req.Content, conversationTitleLength)
+		}
+		return tx.Save(&conversation).Error
+	})
+	if err != nil {
+		zap.S().Errorf("保存对话消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "保存对话消息失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.SendMessageResponse{
+		UserMessage:      userMessage,
+		AssistantMessage: assistantMessage,
+		Usage:            chatResp.Usage,
+	}, "发送消息成功"))
+}
+
+// findConversation 查询对话，不存在或查询失败时直接写入错误响应
+func findConversation(c *gin.Context, database *gorm.DB, conversationID string) (models.Conversation, bool) {
+	var conversation models.Conversation
+	if err := database.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.S().Warnf("请求的对话不存在: %s", conversationID)
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "对话不存在"))
+		} else {
+			zap.S().Errorf("查询对话失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询对话失败: "+err.Error()))
+		}
+		return conversation, false
+	}
+	return conversation, true
+}
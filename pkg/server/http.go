@@ -7,6 +7,8 @@ import (
     "net/http"
 
     "myapi/config"
+    "myapi/pkg/audit"
+    "myapi/pkg/vectorstore"
 
     "github.com/gin-contrib/cors"
     "github.com/gin-gonic/gin"
@@ -18,7 +20,7 @@ type Server struct {
     port int
 }
 
-func NewServer(cfg *config.GlobalConfig) *Server {
+func NewServer(cfg *config.GlobalConfig, store vectorstore.Store, auditor *audit.Logger) *Server {
     server := &Server{
         port: cfg.Port,
     }
@@ -26,8 +28,13 @@ func NewServer(cfg *config.GlobalConfig) *Server {
     gin.SetMode(gin.ReleaseMode)
     engine := gin.Default()
 
-    engine.Use(cors.Default())
-    InitRouter(engine)
+    // 允许跨域请求携带乐观锁、审计和导入导出口令相关的请求头，并读取ETag和下载文件名
+    corsConfig := cors.DefaultConfig()
+    corsConfig.AllowAllOrigins = true
+    corsConfig.AddAllowHeaders("If-Match", "X-Actor", "X-Request-ID", "X-Secret-Key")
+    corsConfig.AddExposeHeaders("ETag", "X-Request-ID", "Content-Disposition")
+    engine.Use(cors.New(corsConfig))
+    InitRouter(engine, store, auditor)
     server.srv = &http.Server{
         Addr:    fmt.Sprintf(":%d", server.port),
         Handler: engine,
@@ -35,6 +42,16 @@ func NewServer(cfg *config.GlobalConfig) *Server {
 
     return server
 }
+
+// NewLocalHandler 创建只在进程内调用的接口处理器，供命令行直接连接数据库时复用接口的校验和修订逻辑，不挂载向量存储
+func NewLocalHandler(auditor *audit.Logger) http.Handler {
+    gin.SetMode(gin.ReleaseMode)
+    engine := gin.New()
+    engine.Use(gin.Recovery())
+    InitRouter(engine, nil, auditor)
+    return engine
+}
+
 func (srv *Server) Run() error {
     err := srv.srv.ListenAndServe()
     if err != nil {
@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"myapi/pkg/db"
+	"myapi/pkg/ingest"
+	"myapi/pkg/kb"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+	"myapi/pkg/vectorstore"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxDocumentSize 上传文档的最大字节数
+const maxDocumentSize = 20 << 20
+
+// collectionNamePattern Milvus集合名称规则：字母或下划线开头，只包含字母、数字和下划线
+var collectionNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,254}$`)
+
+// KnowledgeBaseHandler 知识库相关的处理器
+type KnowledgeBaseHandler struct {
+	service *kb.Service
+}
+
+// NewKnowledgeBaseHandler 创建新的知识库处理器
+func NewKnowledgeBaseHandler(store vectorstore.Store) *KnowledgeBaseHandler {
+	return &KnowledgeBaseHandler{
+		service: kb.NewService(store),
+	}
+}
+
+// CreateKnowledgeBase 创建知识库
+func (h *KnowledgeBaseHandler) CreateKnowledgeBase(c *gin.Context) {
+	var req models.CreateKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("创建知识库参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	knowledgeBase := models.KnowledgeBase{
+		KBID:             uuid.New().String(),
+		Name:             req.Name,
+		Description:      req.Description,
+		EmbeddingModelID: req.EmbeddingModelID,
+		EmbeddingModel:   req.EmbeddingModel,
+		Collection:       req.Collection,
+		ChunkSize:        req.ChunkSize,
+		ChunkOverlap:     req.ChunkOverlap,
+	}
+	if knowledgeBase.Collection == "" {
+		knowledgeBase.Collection = "kb_" + strings.ReplaceAll(knowledgeBase.KBID, "-", "")
+	}
+	if knowledgeBase.ChunkSize == 0 {
+		knowledgeBase.ChunkSize = kb.DefaultChunkSize
+		if knowledgeBase.ChunkOverlap == 0 {
+			knowledgeBase.ChunkOverlap = kb.DefaultChunkOverlap
+		}
+	}
+	if !collectionNamePattern.MatchString(knowledgeBase.Collection) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 集合名称只能包含字母、数字和下划线，且不能以数字开头"))
+		return
+	}
+	if knowledgeBase.ChunkOverlap >= knowledgeBase.ChunkSize {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: chunk_overlap 必须小于 chunk_size"))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 检查嵌入模型是否可用
+	if _, err := ingest.LoadEmbeddingModel(ctx, knowledgeBase.EmbeddingModelID); err != nil {
+		writeKnowledgeBaseError(c, "检查嵌入模型失败", err)
+		return
+	}
+
+	// 检查知识库名称和集合是否已被使用
+	var count int64
+	if err := database.Model(&models.KnowledgeBase{}).Where("name = ? OR collection = ?", knowledgeBase.Name, knowledgeBase.Collection).Count(&count).Error; err != nil {
+		zap.S().Errorf("查询知识库失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库失败: "+err.Error()))
+		return
+	}
+	if count > 0 {
+		zap.S().Warnf("尝试创建重复的知识库: %s", knowledgeBase.Name)
+		c.JSON(http.StatusConflict, models.NewErrorResponse(409, "知识库名称或集合已存在"))
+		return
+	}
+
+	if err := database.Create(&knowledgeBase).Error; err != nil {
+		zap.S().Errorf("创建知识库失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "创建知识库失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功创建知识库: %s, ID: %s", knowledgeBase.Name, knowledgeBase.KBID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(knowledgeBase, "成功创建知识库"))
+}
+
+// GetKnowledgeBases 获取知识库列表
+func (h *KnowledgeBaseHandler) GetKnowledgeBases(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var knowledgeBaseList []models.KnowledgeBase
+	var total int64
+	query := database.Model(&models.KnowledgeBase{})
+	if err := query.Count(&total).Error; err != nil {
+		zap.S().Errorf("查询知识库总数失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库总数失败: "+err.Error()))
+		return
+	}
+	if err := query.Order("created_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&knowledgeBaseList).Error; err != nil {
+		zap.S().Errorf("查询知识库列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"list":      knowledgeBaseList,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "查询知识库列表成功"))
+}
+
+// GetKnowledgeBase 获取单个知识库及其文档
+func (h *KnowledgeBaseHandler) GetKnowledgeBase(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var documentList []models.KBDocument
+	if err := database.Where("kb_id = ?", knowledgeBase.KBID).Order("created_at ASC").Find(&documentList).Error; err != nil {
+		zap.S().Errorf("查询知识库文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库文档失败: "+err.Error()))
+		return
+	}
+
+	chunkCount, err := h.service.ChunkCount(ctx, &knowledgeBase)
+	if err != nil {
+		zap.S().Warnf("统计知识库[%s]分块数失败: %v", knowledgeBase.KBID, err)
+		chunkCount = -1
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"knowledge_base": knowledgeBase,
+		"documents":      documentList,
+		"chunk_count":    chunkCount,
+	}, "查询成功"))
+}
+
+// DeleteKnowledgeBase 删除知识库、文档及其向量集合
+func (h *KnowledgeBaseHandler) DeleteKnowledgeBase(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.service.Drop(ctx, &knowledgeBase); err != nil {
+		writeKnowledgeBaseError(c, "删除知识库集合失败", err)
+		return
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kb_id = ?", knowledgeBase.KBID).Delete(&models.KBDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&knowledgeBase).Error
+	})
+	if err != nil {
+		zap.S().Errorf("删除知识库失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除知识库失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功删除知识库: %s", knowledgeBase.KBID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(knowledgeBase, "成功删除知识库"))
+}
+
+// AddDocument 上传文档，支持JSON正文或multipart的file字段，切分、向量化后写入知识库
+func (h *KnowledgeBaseHandler) AddDocument(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	req, ok := bindDocumentRequest(c)
+	if !ok {
+		return
+	}
+	format := kb.NormalizeFormat(req.Format)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 不支持的文档格式 "+req.Format))
+		return
+	}
+	title, text, err := kb.ExtractText(format, req.Content)
+	if err != nil {
+		writeKnowledgeBaseError(c, "解析文档失败", err)
+		return
+	}
+	if req.Title != "" {
+		title = req.Title
+	}
+
+	document := models.KBDocument{
+		DocumentID: uuid.New().String(),
+		KBID:       knowledgeBase.KBID,
+		Title:      title,
+		Format:     format,
+		CharCount:  len([]rune(text)),
+	}
+	if document.ChunkCount, err = h.service.AddDocument(ctx, &knowledgeBase, document.DocumentID, title, text); err != nil {
+		writeKnowledgeBaseError(c, "文档入库失败", err)
+		return
+	}
+	if err := database.Create(&document).Error; err != nil {
+		zap.S().Errorf("保存知识库文档失败: %v", err)
+		if err := h.service.DeleteDocument(ctx, &knowledgeBase, document.DocumentID); err != nil {
+			zap.S().Errorf("清理文档[%s]分块失败: %v", document.DocumentID, err)
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "保存知识库文档失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("知识库[%s]成功添加文档: %s, 分块数: %d", knowledgeBase.KBID, document.DocumentID, document.ChunkCount)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(document, "成功添加文档"))
+}
+
+// DeleteDocument 删除知识库中的文档及其分块
+func (h *KnowledgeBaseHandler) DeleteDocument(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var document models.KBDocument
+	if err := database.Where("kb_id = ? AND document_id = ?", knowledgeBase.KBID, c.Param("doc_id")).First(&document).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "文档不存在"))
+		} else {
+			zap.S().Errorf("查询知识库文档失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库文档失败: "+err.Error()))
+		}
+		return
+	}
+
+	if err := h.service.DeleteDocument(ctx, &knowledgeBase, document.DocumentID); err != nil {
+		writeKnowledgeBaseError(c, "删除文档分块失败", err)
+		return
+	}
+	if err := database.Delete(&document).Error; err != nil {
+		zap.S().Errorf("删除知识库文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除知识库文档失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("知识库[%s]成功删除文档: %s", knowledgeBase.KBID, document.DocumentID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(document, "成功删除文档"))
+}
+
+// QueryKnowledgeBase 检索知识库并使用指定的对话模型回答问题，返回引用的分块
+func (h *KnowledgeBaseHandler) QueryKnowledgeBase(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req models.KBQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("知识库问答参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	var chatModel models.Model
+	if err := database.Where("model_id = ?", req.ModelID).First(&chatModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	resp, err := h.service.Query(ctx, &knowledgeBase, &chatModel, &req)
+	if err != nil {
+		writeKnowledgeBaseError(c, "知识库问答失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(resp, "问答成功"))
+}
+
+// SearchKnowledgeBase 检索知识库中与查询最相关的分块，可选使用重排序模型
+func (h *KnowledgeBaseHandler) SearchKnowledgeBase(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	knowledgeBase, ok := findKnowledgeBase(c, database, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req models.KBSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("知识库检索参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	chunks, err := h.service.Retrieve(ctx, &knowledgeBase, req.Query, req.KBRetrieveOptions)
+	if err != nil {
+		writeKnowledgeBaseError(c, "知识库检索失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.KBSearchResponse{Chunks: chunks}, "检索成功"))
+}
+
+// bindDocumentRequest 解析上传的文档，multipart请求未指定格式时按文件扩展名推断
+func bindDocumentRequest(c *gin.Context) (models.AddKBDocumentRequest, bool) {
+	var req models.AddKBDocumentRequest
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			zap.S().Errorf("上传文档参数绑定错误: %v", err)
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+			return req, false
+		}
+		return req, true
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 缺少上传的文件"))
+		return req, false
+	}
+	if file.Size > maxDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(413, "文档大小超过限制"))
+		return req, false
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "读取上传的文件失败: "+err.Error()))
+		return req, false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "读取上传的文件失败: "+err.Error()))
+		return req, false
+	}
+
+	req.Title = c.PostForm("title")
+	req.Format = c.PostForm("format")
+	if req.Format == "" {
+		req.Format = kb.FormatFromFilename(file.Filename)
+	}
+	req.Content = string(content)
+	return req, true
+}
+
+// writeKnowledgeBaseError 按错误类型写入响应：输入或配置错误为400，模型拒绝请求时按llm.RejectionStatus，上游模型错误为502
+func writeKnowledgeBaseError(c *gin.Context, action string, err error) {
+	var tooLong *llm.ContextTooLongError
+	var upstream *llm.UpstreamError
+	status, rejected := llm.RejectionStatus(err)
+	switch {
+	case errors.Is(err, ingest.ErrModelNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+	case ingest.IsPermanent(err), errors.Is(err, kb.ErrNotRerankModel), errors.As(err, &tooLong):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+	case rejected:
+		c.JSON(status, models.NewErrorResponse(status, err.Error()))
+	case errors.As(err, &upstream):
+		zap.S().Errorf("%s: %v", action, err)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(502, err.Error()))
+	default:
+		zap.S().Errorf("%s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, action+": "+err.Error()))
+	}
+}
+
+// findKnowledgeBase 查询知识库，不存在或查询失败时直接写入错误响应
+func findKnowledgeBase(c *gin.Context, database *gorm.DB, kbID string) (models.KnowledgeBase, bool) {
+	var knowledgeBase models.KnowledgeBase
+	if err := database.Where("kb_id = ?", kbID).First(&knowledgeBase).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.S().Warnf("请求的知识库不存在: %s", kbID)
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "知识库不存在"))
+		} else {
+			zap.S().Errorf("查询知识库失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询知识库失败: "+err.Error()))
+		}
+		return knowledgeBase, false
+	}
+	return knowledgeBase, true
+}
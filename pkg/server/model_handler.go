@@ -1,18 +1,18 @@
 package server
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"github.com/google/uuid"
 	"io"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"myapi/pkg/db"
+	"myapi/pkg/llm"
 	"myapi/pkg/models"
+	"myapi/pkg/tokenizer"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -45,26 +45,72 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 		Timeout:    req.Timeout,
 		Type:       req.Type,
 		Dimensions: req.Dimensions,
+
+		ContextWindow:     req.ContextWindow,
+		MaxOutputTokens:   req.MaxOutputTokens,
+		ContextStrategy:   req.ContextStrategy,
+		SummarizerModelID: req.SummarizerModelID,
+		SummarizerModel:   req.SummarizerModel,
+		Tokenizer:         req.Tokenizer,
+
+		SupportsVision:    req.SupportsVision,
+		SupportsTools:     req.SupportsTools,
+		SupportsJSONMode:  req.SupportsJSONMode,
+		SupportsStreaming: req.SupportsStreaming,
+
+		ScoreNormalization: req.ScoreNormalization,
+
+		Enabled: true,
+		Version: 1,
 	}
 
 	ctx := context.Background()
 	database := db.GetDBWithContext(ctx)
 
-	// 检查模型名是否已存在
-	var existingModel models.Model
-	if err := database.Where("name = ?", model.Name).First(&existingModel).Error; err == nil {
-		zap.S().Warnf("尝试创建重复模型名称: %s", model.Name)
-		c.JSON(http.StatusConflict, models.NewErrorResponse(409, "模型名称已存在"))
+	if msg := validateModelConfig(database, nil, &model); msg != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+msg))
 		return
 	}
+
+	// verify=true 时先测试连通性，未通过则不保存
+	if verify, _ := strconv.ParseBool(c.Query("verify")); verify {
+		result, err := llm.Probe(ctx, &model, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+			return
+		}
+		if !result.Success {
+			zap.S().Warnf("模型%s连通性测试未通过: %s", model.Name, result.Error)
+			resp := models.NewErrorResponse(400, "模型连通性测试未通过: "+result.Error)
+			resp.Data = result
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
 	model.ModelID = uuid.New().String()
-	// 创建模型
-	if err := database.Create(&model).Error; err != nil {
+	// 创建模型，名称唯一由数据库约束保证，已删除的模型仍占用名称
+	err := saveModelChange(c, database, models.RevisionActionCreate, nil, &model, func(tx *gorm.DB) error {
+		if err := tx.Create(&model).Error; err != nil {
+			if db.IsDuplicateKeyError(err) {
+				return errNameConflict
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errNameConflict) {
+			zap.S().Warnf("尝试创建重复模型名称: %s", model.Name)
+			writeNameConflict(c, database, model.Name)
+			return
+		}
 		zap.S().Errorf("创建模型失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "创建模型失败: "+err.Error()))
 		return
 	}
 
+	c.Set(auditModelIDKey, model.ModelID)
+	c.Header("ETag", modelETag(&model))
 	zap.S().Infof("成功创建模型: %s, ID: %s", model.Name, model.ModelID)
 	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功创建模型"))
 }
@@ -88,46 +134,63 @@ func (h *ModelHandler) GetModel(c *gin.Context) {
 		return
 	}
 
+	list := []models.Model{model}
+	attachModelStatus(database, list)
+	model = list[0]
+	c.Header("ETag", modelETag(&model))
 	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "查询成功"))
 }
 
-// GetModels 获取模型列表
+// GetModels 获取模型列表，支持按类型、名称、启用状态和健康状态过滤，按页码或游标分页
 func (h *ModelHandler) GetModels(c *gin.Context) {
-	ctx := context.Background()
-	database := db.GetDBWithContext(ctx)
-
-	// 解析分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	if page < 1 {
-		page = 1
+	var query models.ModelListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
 	}
-	if pageSize < 1 {
-		pageSize = 10
+	if err := normalizeModelListQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
 	}
 
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+	filtered := filterModels(database, &query)
+
 	var modelList []models.Model
 	var total int64
 
 	// 查询总数
-	if err := database.Model(&models.Model{}).Count(&total).Error; err != nil {
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		zap.S().Errorf("查询模型总数失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型总数失败: "+err.Error()))
 		return
 	}
 
-	// 分页查询
-	if err := database.Limit(pageSize).Offset((page - 1) * pageSize).Find(&modelList).Error; err != nil {
+	// 分页查询，多查一条用于判断是否还有下一页
+	paged, err := pageModels(filtered.Session(&gorm.Session{}), &query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+	if err := paged.Limit(query.PageSize + 1).Find(&modelList).Error; err != nil {
 		zap.S().Errorf("查询模型列表失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型列表失败: "+err.Error()))
 		return
 	}
+	var nextCursor string
+	if len(modelList) > query.PageSize {
+		modelList = modelList[:query.PageSize]
+		nextCursor = encodeModelCursor(&query, &modelList[len(modelList)-1])
+	}
+	attachModelStatus(database, modelList)
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
-		"list":      modelList,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
+		"list":        modelList,
+		"total":       total,
+		"page":        query.Page,
+		"page_size":   query.PageSize,
+		"next_cursor": nextCursor,
 	}, "查询模型列表成功"))
 }
 
@@ -149,6 +212,9 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 		}
 		return
 	}
+	if !checkIfMatch(c, &model) {
+		return
+	}
 
 	var req models.UpdateModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -157,17 +223,8 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 		return
 	}
 
-	// 检查名称是否与其他模型冲突
-	if req.Name != nil && *req.Name != model.Name {
-		var existingModel models.Model
-		if err := database.Where("name = ? AND model_id != ?", *req.Name, modelID).First(&existingModel).Error; err == nil {
-			zap.S().Warnf("尝试更新为已存在的模型名称: %s", *req.Name)
-			c.JSON(http.StatusConflict, models.NewErrorResponse(409, "模型名称已存在"))
-			return
-		}
-	}
-
 	// 只更新提供的字段
+	before := model
 	if req.Name != nil {
 		model.Name = *req.Name
 	}
@@ -186,18 +243,66 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 	if req.Dimensions != nil {
 		model.Dimensions = *req.Dimensions
 	}
+	if req.ContextWindow != nil {
+		model.ContextWindow = *req.ContextWindow
+	}
+	if req.MaxOutputTokens != nil {
+		model.MaxOutputTokens = *req.MaxOutputTokens
+	}
+	if req.ContextStrategy != nil {
+		model.ContextStrategy = *req.ContextStrategy
+	}
+	if req.SummarizerModelID != nil {
+		model.SummarizerModelID = *req.SummarizerModelID
+	}
+	if req.SummarizerModel != nil {
+		model.SummarizerModel = *req.SummarizerModel
+	}
+	if req.Tokenizer != nil {
+		model.Tokenizer = *req.Tokenizer
+	}
+	if req.SupportsVision != nil {
+		model.SupportsVision = *req.SupportsVision
+	}
+	if req.SupportsTools != nil {
+		model.SupportsTools = *req.SupportsTools
+	}
+	if req.SupportsJSONMode != nil {
+		model.SupportsJSONMode = *req.SupportsJSONMode
+	}
+	if req.SupportsStreaming != nil {
+		model.SupportsStreaming = *req.SupportsStreaming
+	}
+	if req.ScoreNormalization != nil {
+		model.ScoreNormalization = *req.ScoreNormalization
+	}
+	if msg := validateModelConfig(database, &before, &model); msg != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+msg))
+		return
+	}
 
-	if err := database.Save(&model).Error; err != nil {
-		zap.S().Errorf("更新模型失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "更新模型失败: "+err.Error()))
+	err := saveModelChange(c, database, models.RevisionActionUpdate, &before, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &before, &model)
+	})
+	if err != nil {
+		if errors.Is(err, errNameConflict) {
+			zap.S().Warnf("尝试更新为已存在的模型名称: %s", model.Name)
+			writeNameConflict(c, database, model.Name)
+			return
+		}
+		writeModelChangeError(c, "更新模型", err)
 		return
 	}
 
+	// 配置变化后之前的健康状态不再可信，等待下一轮探测
+	resetModelStatus(database, model.ModelID)
+
 	zap.S().Infof("成功更新模型: %s, ID: %s", model.Name, model.ModelID)
+	c.Header("ETag", modelETag(&model))
 	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功更新模型"))
 }
 
-// DeleteModel 删除模型
+// DeleteModel 删除模型，软删除后可以恢复
 func (h *ModelHandler) DeleteModel(c *gin.Context) {
 	modelID := c.Param("id")
 
@@ -215,65 +320,241 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 		}
 		return
 	}
+	if !checkIfMatch(c, &model) {
+		return
+	}
 
-	if err := database.Delete(&model).Error; err != nil {
-		zap.S().Errorf("删除模型失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除模型失败: "+err.Error()))
+	before := model
+	model.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	err := saveModelChange(c, database, models.RevisionActionDelete, &before, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &before, &model)
+	})
+	if err != nil {
+		writeModelChangeError(c, "删除模型", err)
 		return
 	}
 
+	resetModelStatus(database, model.ModelID)
+
 	zap.S().Infof("成功删除模型: %s, ID: %s", model.Name, model.ModelID)
 	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功删除模型"))
 }
 
-// ChatWithModel 大模型对话接口
-func (h *ModelHandler) ChatWithModel(c *gin.Context) {
+// DisableModel 停用模型，停用后不再转发请求
+func (h *ModelHandler) DisableModel(c *gin.Context) {
+	h.setModelEnabled(c, false)
+}
+
+// EnableModel 启用模型
+func (h *ModelHandler) EnableModel(c *gin.Context) {
+	h.setModelEnabled(c, true)
+}
+
+func (h *ModelHandler) setModelEnabled(c *gin.Context, enabled bool) {
 	modelID := c.Param("id")
+	action := "启用"
+	if !enabled {
+		action = "停用"
+	}
+
 	ctx := context.Background()
 	database := db.GetDBWithContext(ctx)
 
-	// 查找模型
 	var model models.Model
 	if err := database.Where("model_id = ?", modelID).First(&model).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
 		return
 	}
 
-	// 解析提问内容
-	var req models.ChatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+	if !checkIfMatch(c, &model) {
 		return
 	}
 
-	// 构造大模型API请求
-	payload, err := json.Marshal(req)
+	before := model
+	model.Enabled = enabled
+	revisionAction := models.RevisionActionEnable
+	if !enabled {
+		revisionAction = models.RevisionActionDisable
+	}
+	err := saveModelChange(c, database, revisionAction, &before, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &before, &model)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "请求序列化失败: "+err.Error()))
+		writeModelChangeError(c, action+"模型", err)
 		return
 	}
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", model.Endpoint, bytes.NewBuffer(payload))
+
+	zap.S().Infof("成功%s模型: %s, ID: %s", action, model.Name, model.ModelID)
+	c.Header("ETag", modelETag(&model))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功"+action+"模型"))
+}
+
+// RestoreModel 恢复已删除的模型
+func (h *ModelHandler) RestoreModel(c *gin.Context) {
+	modelID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var model models.Model
+	if err := database.Unscoped().Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+	if !model.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, models.NewErrorResponse(409, "模型未被删除"))
+		return
+	}
+	if !checkIfMatch(c, &model) {
+		return
+	}
+
+	before := model
+	model.DeletedAt = gorm.DeletedAt{}
+	err := saveModelChange(c, database, models.RevisionActionRestore, &before, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &before, &model)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "请求创建失败: "+err.Error()))
+		writeModelChangeError(c, "恢复模型", err)
 		return
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	apiKey := strings.TrimSpace(model.APIKey)
-	if apiKey == "" {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "API Key 为空"))
+	zap.S().Infof("成功恢复模型: %s, ID: %s", model.Name, model.ModelID)
+	c.Header("ETag", modelETag(&model))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功恢复模型"))
+}
+
+// PurgeModel 彻底删除模型，包括已软删除的模型，删除后无法恢复
+func (h *ModelHandler) PurgeModel(c *gin.Context) {
+	modelID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var model models.Model
+	if err := database.Unscoped().Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	if !checkIfMatch(c, &model) {
 		return
 	}
-	if strings.ContainsAny(apiKey, "\r\n\t ") {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "API Key 包含非法字符"))
+
+	// 修订记录保留，彻底删除后仍可追溯
+	err := saveModelChange(c, database, models.RevisionActionPurge, &model, &model, func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("version = ?", model.Version).Delete(&model)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		return result.Error
+	})
+	if err != nil {
+		writeModelChangeError(c, "彻底删除模型", err)
 		return
 	}
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	resetModelStatus(database, model.ModelID)
+
+	zap.S().Infof("成功彻底删除模型: %s, ID: %s", model.Name, model.ModelID)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功彻底删除模型"))
+}
+
+// TestModel 按模型类型发送最小请求，测试模型的连通性和鉴权
+func (h *ModelHandler) TestModel(c *gin.Context) {
+	modelID := c.Param("id")
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var model models.Model
+	if err := database.Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	// 请求体可选，只用于指定上游模型名
+	var req models.ModelTestRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+			return
+		}
+	}
+
+	result, err := llm.Probe(ctx, &model, req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	msg := "连通性测试通过"
+	if !result.Success {
+		msg = "连通性测试未通过"
+		zap.S().Warnf("模型%s连通性测试未通过: %s", model.Name, result.Error)
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, msg))
+}
+
+// ChatWithModel 大模型对话接口
+func (h *ModelHandler) ChatWithModel(c *gin.Context) {
+	modelID := c.Param("id")
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 查找模型，已删除或停用的模型直接拒绝
+	model, ok := findCallableModel(c, database.Where("model_id = ?", modelID))
+	if !ok {
+		return
+	}
+
+	// 解析提问内容
+	var req models.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	// 按模型的类型和能力标记校验请求
+	if err := llm.CheckChatRequest(&model, &req); err != nil {
+		writeUpstreamCallError(c, err)
+		return
+	}
+
+	// 按模型的上下文策略处理过长的消息
+	messages, err := llm.FitContext(ctx, &model, req.Model, req.Messages, models.ModelLoader(database))
+	if err != nil {
+		var tooLong *llm.ContextTooLongError
+		if errors.As(err, &tooLong) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "处理上下文失败: "+err.Error()))
+		}
+		return
+	}
+	req.Messages = messages
 
 	// 发起请求
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := llm.Do(ctx, &model, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "大模型请求失败: "+err.Error()))
+		writeUpstreamCallError(c, err)
 		return
 	}
 	defer func(Body io.ReadCloser) {
@@ -282,8 +563,142 @@ func (h *ModelHandler) ChatWithModel(c *gin.Context) {
 
 		}
 	}(resp.Body)
+	if req.Stream {
+		streamResponse(c, resp)
+		return
+	}
 	body, _ := io.ReadAll(resp.Body)
 
 	// 直接返回大模型响应
 	c.Data(resp.StatusCode, "application/json", body)
 }
+
+// streamResponse 将上游的流式响应边读边转发给客户端
+func streamResponse(c *gin.Context, resp *http.Response) {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/event-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Status(resp.StatusCode)
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := c.Writer.Write(buf[:n]); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				zap.S().Warnf("转发流式响应中断: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// TokenizeChat 计算对话请求在模型对应编码下的token数
+func (h *ModelHandler) TokenizeChat(c *gin.Context) {
+	modelID := c.Param("id")
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var model models.Model
+	if err := database.Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	var req models.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	t, err := tokenizer.Get(tokenizer.ForModel(&model, req.Model))
+	if err != nil {
+		zap.S().Errorf("加载分词器失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "加载分词器失败: "+err.Error()))
+		return
+	}
+	total, perMessage := t.CountMessages(req.Messages)
+	resp := models.TokenizeResponse{
+		Encoding:        t.Encoding(),
+		PromptTokens:    total,
+		MessageTokens:   perMessage,
+		ContextWindow:   model.ContextWindow,
+		MaxOutputTokens: model.MaxOutputTokens,
+	}
+	if budget := llm.ContextBudget(&model); budget > 0 {
+		resp.RemainingTokens = max(budget-total, 0)
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(resp, "计算成功"))
+}
+
+// writeUpstreamCallError 写入转发请求失败的响应，模型的类型、能力或状态拒绝请求时按llm.RejectionStatus返回状态码
+func writeUpstreamCallError(c *gin.Context, err error) {
+	if status, ok := llm.RejectionStatus(err); ok {
+		c.JSON(status, models.NewErrorResponse(status, err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+}
+
+// findCallableModel 查询要调用的模型，包括已删除的模型；不存在、已删除、停用或不可用时直接写入错误响应
+func findCallableModel(c *gin.Context, query *gorm.DB) (models.Model, bool) {
+	var model models.Model
+	if err := query.Unscoped().First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return model, false
+	}
+	if err := llm.CheckModelState(&model); err != nil {
+		writeUpstreamCallError(c, err)
+		return model, false
+	}
+	return model, true
+}
+
+// attachModelStatus 为模型附加健康检查状态，没有探测记录的模型不附加
+func attachModelStatus(database *gorm.DB, modelList []models.Model) {
+	if len(modelList) == 0 {
+		return
+	}
+	ids := make([]string, len(modelList))
+	for i := range modelList {
+		ids[i] = modelList[i].ModelID
+	}
+	var statuses []models.ModelStatus
+	if err := database.Where("model_id IN ?", ids).Find(&statuses).Error; err != nil {
+		zap.S().Warnf("查询模型健康状态失败: %v", err)
+		return
+	}
+	byID := make(map[string]*models.ModelStatus, len(statuses))
+	for i := range statuses {
+		byID[statuses[i].ModelID] = &statuses[i]
+	}
+	for i := range modelList {
+		modelList[i].Status = byID[modelList[i].ModelID]
+	}
+}
+
+// resetModelStatus 清除模型的健康状态和down标记
+func resetModelStatus(database *gorm.DB, modelID string) {
+	llm.SetModelDown(modelID, false)
+	if err := database.Where("model_id = ?", modelID).Delete(&models.ModelStatus{}).Error; err != nil {
+		zap.S().Warnf("清除模型[%s]健康状态失败: %v", modelID, err)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TestCreateModelConcurrentSameName 并发创建同名模型时只有一个请求成功，其余返回409
+func TestCreateModelConcurrentSameName(t *testing.T) {
+	dbtest.Setup(t)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := NewLocalHandler(auditor)
+
+	const n = 16
+	body, _ := json.Marshal(models.CreateModelRequest{
+		Name:     "concurrent-" + uuid.New().String(),
+		Endpoint: "http://127.0.0.1:9/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     models.ModelTypeChat,
+	})
+
+	codes := make([]int, n)
+	bodies := make([]string, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/models/create", bytes.NewReader(body)))
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	counts := make(map[int]int)
+	for i, code := range codes {
+		counts[code]++
+		if code != http.StatusOK && code != http.StatusConflict {
+			t.Errorf("第%d个请求返回%d: %s", i, code, bodies[i])
+		}
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != n-1 {
+		t.Errorf("返回状态码统计为%v，期望1个200和%d个409", counts, n-1)
+	}
+}
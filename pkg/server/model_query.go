@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 模型列表的分页大小
+const (
+	defaultModelPageSize = 10
+	maxModelPageSize     = 100
+)
+
+// modelSortColumns 模型列表支持的排序字段
+var modelSortColumns = map[string]bool{
+	"name":       true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// likeEscaper 转义LIKE模式中的通配符，转义符使用各数据库都没有特殊含义的!
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// modelCursor 游标中保存的上一页最后一条记录的位置，排序方式不同的游标不能混用
+type modelCursor struct {
+	Sort    string `json:"s"`
+	Order   string `json:"o"`
+	Value   string `json:"v"`
+	ModelID string `json:"id"`
+}
+
+// normalizeModelListQuery 校验查询参数并补全默认值
+func normalizeModelListQuery(q *models.ModelListQuery) error {
+	q.Type = strings.ToLower(strings.TrimSpace(q.Type))
+	if q.Type != "" && !models.IsValidModelType(q.Type) {
+		return errors.Errorf("不支持的模型类型: %s", q.Type)
+	}
+	switch q.Status {
+	case "", models.ModelStatusHealthy, models.ModelStatusDegraded, models.ModelStatusDown, models.ModelStatusUnknown:
+	default:
+		return errors.Errorf("不支持的健康状态: %s，可选值为 healthy|degraded|down|unknown", q.Status)
+	}
+	if q.Sort == "" {
+		q.Sort = "created_at"
+	}
+	if !modelSortColumns[q.Sort] {
+		return errors.Errorf("不支持的排序字段: %s，可选值为 name|created_at|updated_at", q.Sort)
+	}
+	q.Order = strings.ToLower(q.Order)
+	if q.Order == "" {
+		q.Order = "asc"
+	}
+	if q.Order != "asc" && q.Order != "desc" {
+		return errors.Errorf("不支持的排序方向: %s，可选值为 asc|desc", q.Order)
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = defaultModelPageSize
+	}
+	q.PageSize = min(q.PageSize, maxModelPageSize)
+	return nil
+}
+
+// filterModels 按类型、名称、启用状态和健康状态过滤
+func filterModels(database *gorm.DB, q *models.ModelListQuery) *gorm.DB {
+	if q.IncludeDeleted {
+		database = database.Unscoped()
+	}
+	database = database.Model(&models.Model{})
+	if q.Type != "" {
+		database = database.Where("type = ?", q.Type)
+	}
+	if q.Name != "" {
+		// 各数据库LIKE的大小写规则不同，统一按小写匹配
+		database = database.Where("LOWER(name) LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(strings.ToLower(q.Name))+"%")
+	}
+	if q.Enabled != nil {
+		database = database.Where("enabled = ?", *q.Enabled)
+	}
+	switch q.Status {
+	case "":
+	case models.ModelStatusUnknown:
+		database = database.Where("model_id NOT IN (?)", database.Session(&gorm.Session{NewDB: true}).Model(&models.ModelStatus{}).Select("model_id"))
+	default:
+		database = database.Where("model_id IN (?)", database.Session(&gorm.Session{NewDB: true}).Model(&models.ModelStatus{}).Select("model_id").Where("status = ?", q.Status))
+	}
+	return database
+}
+
+// pageModels 按排序字段和model_id排序分页，游标不为空时从游标位置之后开始
+func pageModels(database *gorm.DB, q *models.ModelListQuery) (*gorm.DB, error) {
+	direction, op := "ASC", ">"
+	if q.Order == "desc" {
+		direction, op = "DESC", "<"
+	}
+	database = database.Order(fmt.Sprintf("%s %s, model_id %s", q.Sort, direction, direction))
+	if q.Cursor == "" {
+		return database.Offset((q.Page - 1) * q.PageSize), nil
+	}
+
+	cursor, err := decodeModelCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Sort != q.Sort || cursor.Order != q.Order {
+		return nil, errors.New("游标与当前的排序方式不一致")
+	}
+	var value any = cursor.Value
+	if q.Sort != "name" {
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, errors.New("游标不合法")
+		}
+		value = t
+	}
+	return database.Where(fmt.Sprintf("(%s %s ?) OR (%s = ? AND model_id %s ?)", q.Sort, op, q.Sort, op), value, value, cursor.ModelID), nil
+}
+
+// encodeModelCursor 生成指向model之后的游标
+func encodeModelCursor(q *models.ModelListQuery, model *models.Model) string {
+	cursor := modelCursor{Sort: q.Sort, Order: q.Order, ModelID: model.ModelID}
+	switch q.Sort {
+	case "name":
+		cursor.Value = model.Name
+	case "updated_at":
+		cursor.Value = model.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = model.CreatedAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeModelCursor(s string) (*modelCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("游标不合法")
+	}
+	var cursor modelCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ModelID == "" {
+		return nil, errors.New("游标不合法")
+	}
+	return &cursor, nil
+}
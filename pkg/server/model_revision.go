@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// anonymousActor 请求未标明操作人时记录的操作人
+const anonymousActor = "anonymous"
+
+// requestActor 从请求头X-Actor读取操作人，未提供时为anonymous
+func requestActor(c *gin.Context) string {
+	if actor := c.GetHeader("X-Actor"); actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// saveModelChange 在同一事务中执行模型变更并写入修订记录，before为nil表示新建
+func saveModelChange(c *gin.Context, database *gorm.DB, action string, before, after *models.Model, change func(tx *gorm.DB) error) error {
+	return saveModelChangeAs(database, requestActor(c), action, before, after, change)
+}
+
+// saveModelChangeAs 同saveModelChange，用于没有HTTP请求的场景，由调用方指定操作人
+func saveModelChangeAs(database *gorm.DB, actor, action string, before, after *models.Model, change func(tx *gorm.DB) error) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		var last int
+		if err := tx.Model(&models.ModelRevision{}).Where("model_id = ?", after.ModelID).
+			Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ModelRevision{
+			RevisionID: uuid.New().String(),
+			ModelID:    after.ModelID,
+			Revision:   last + 1,
+			Action:     action,
+			Actor:      actor,
+			Snapshot:   models.NewModelSnapshot(after),
+			Diff:       models.DiffModels(before, after),
+		}).Error
+	})
+}
+
+// GetModelRevisions 获取模型的修订记录，按修订号倒序，包括已删除的模型
+func (h *ModelHandler) GetModelRevisions(c *gin.Context) {
+	modelID := c.Param("id")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var revisions []models.ModelRevision
+	if err := database.Where("model_id = ?", modelID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		zap.S().Errorf("查询模型修订记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型修订记录失败: "+err.Error()))
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型没有修订记录"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(revisions, "查询成功"))
+}
+
+// RollbackModel 将模型配置回滚到指定修订，API Key、启用和删除状态保持不变
+func (h *ModelHandler) RollbackModel(c *gin.Context) {
+	modelID := c.Param("id")
+
+	var req models.RollbackModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var model models.Model
+	if err := database.Where("model_id = ?", modelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "模型不存在"))
+		} else {
+			zap.S().Errorf("查询模型失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型失败: "+err.Error()))
+		}
+		return
+	}
+
+	if !checkIfMatch(c, &model) {
+		return
+	}
+
+	var revision models.ModelRevision
+	if err := database.Where("model_id = ? AND revision = ?", modelID, req.Revision).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "修订记录不存在"))
+		} else {
+			zap.S().Errorf("查询模型修订记录失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型修订记录失败: "+err.Error()))
+		}
+		return
+	}
+
+	before := model
+	revision.Snapshot.ApplyTo(&model)
+	if msg := validateModelConfig(database, &before, &model); msg != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "无法回滚: "+msg))
+		return
+	}
+	err := saveModelChange(c, database, models.RevisionActionRollback, &before, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &before, &model)
+	})
+	if err != nil {
+		if errors.Is(err, errNameConflict) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse(409, "无法回滚: 模型名称已被其他模型使用"))
+			return
+		}
+		writeModelChangeError(c, "回滚模型", err)
+		return
+	}
+	resetModelStatus(database, model.ModelID)
+
+	zap.S().Infof("成功回滚模型: %s, ID: %s, 修订: %d", model.Name, model.ModelID, req.Revision)
+	c.Header("ETag", modelETag(&model))
+	c.JSON(http.StatusOK, models.NewSuccessResponse(model, "成功回滚模型"))
+}
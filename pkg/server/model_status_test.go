@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TestGetModelStatus 查询单个模型和模型列表时都返回健康状态
+func TestGetModelStatus(t *testing.T) {
+	dbtest.Setup(t)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := NewLocalHandler(auditor)
+
+	model := models.Model{
+		ModelID:  uuid.New().String(),
+		Name:     "status-" + uuid.New().String(),
+		Endpoint: "http://127.0.0.1:9/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     models.ModelTypeChat,
+		Enabled:  true,
+		Version:  1,
+	}
+	if err := db.GetDB().Create(&model).Error; err != nil {
+		t.Fatalf("写入模型失败: %v", err)
+	}
+	status := models.ModelStatus{ModelID: model.ModelID, Status: models.ModelStatusDown, LastError: "connection refused", ConsecutiveFailures: 3}
+	if err := db.GetDB().Create(&status).Error; err != nil {
+		t.Fatalf("写入健康状态失败: %v", err)
+	}
+
+	get := func(path string, data any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s返回%d: %s", path, rec.Code, rec.Body.String())
+		}
+		resp := struct {
+			Data any `json:"data"`
+		}{Data: data}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+	}
+
+	var got models.Model
+	get("/api/v1/models/"+model.ModelID, &got)
+	if got.Status == nil || got.Status.Status != models.ModelStatusDown || got.Status.ConsecutiveFailures != 3 {
+		t.Errorf("查询单个模型返回的健康状态为%+v，期望down", got.Status)
+	}
+
+	var list struct {
+		List []models.Model `json:"list"`
+	}
+	get("/api/v1/models/get?name="+model.Name, &list)
+	if len(list.List) != 1 || list.List[0].Status == nil || list.List[0].Status.Status != models.ModelStatusDown {
+		t.Errorf("模型列表返回%+v，期望包含down状态", list.List)
+	}
+}
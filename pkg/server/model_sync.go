@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// syncActor 声明式同步写入修订记录时的操作人
+const syncActor = "model-sync"
+
+// syncRetries 同步时与接口请求并发修改同一个模型发生冲突时的重试次数
+const syncRetries = 3
+
+// SyncModelsFromFile 按模型文件同步t_model：新建缺少的模型、更新有变化的模型，按文件配置停用未列出的模型；dryRun时只返回变更计划。
+// 同步时持有数据库锁，多个副本同时启动时依次同步，后同步的副本看到的模型已是最新配置
+func SyncModelsFromFile(path string, dryRun bool) (*models.ModelImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %w", err)
+	}
+	var file models.ModelSyncFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析模型文件失败: %w", err)
+	}
+
+	for i := range file.Models {
+		spec := &file.Models[i]
+		if spec.APIKey != "" || spec.APIKeyEncrypted != "" {
+			return nil, fmt.Errorf("%w: 模型%s的API Key需要通过 api_key_env 引用环境变量", errInvalidImport, spec.Name)
+		}
+		if spec.APIKeyEnv != "" {
+			key, ok := os.LookupEnv(spec.APIKeyEnv)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("%w: 模型%s引用的环境变量%s未设置", errInvalidImport, spec.Name, spec.APIKeyEnv)
+			}
+			spec.APIKey = key
+		}
+		// 文件中列出的模型默认启用
+		if spec.Enabled == nil {
+			enabled := true
+			spec.Enabled = &enabled
+		}
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+	opts := modelImportOptions{
+		DryRun:          dryRun,
+		Actor:           syncActor,
+		DisableUnlisted: file.DisableUnlisted,
+	}
+	if dryRun {
+		return importModels(database, file.Models, opts)
+	}
+
+	var result *models.ModelImportResult
+	err = db.WithLock(ctx, db.ModelSyncLock, func() error {
+		for attempt := 1; ; attempt++ {
+			result, err = importModels(database, file.Models, opts)
+			if err == nil || attempt >= syncRetries || (!errors.Is(err, errVersionConflict) && !errors.Is(err, errNameConflict)) {
+				return err
+			}
+			zap.S().Warnf("同步模型文件时与其他修改冲突，第%d次重试: %v", attempt, err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WatchModelsFile 收到SIGHUP时重新同步模型文件，同步失败只记录日志
+func WatchModelsFile(ctx context.Context, path string) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			zap.S().Infof("收到SIGHUP，重新同步模型文件: %s", path)
+			result, err := SyncModelsFromFile(path, false)
+			if err != nil {
+				zap.S().Errorf("同步模型文件失败: %v", err)
+				continue
+			}
+			LogModelSyncResult(result)
+		}
+	}
+}
+
+// LogModelSyncResult 记录同步结果
+func LogModelSyncResult(result *models.ModelImportResult) {
+	for _, item := range result.Items {
+		if item.Action != models.ImportActionUnchanged {
+			zap.S().Infof("同步模型[%s]: %s", item.Name, item.Action)
+		}
+	}
+	zap.S().Infof("同步模型完成: 新建%d个, 更新%d个, 停用%d个, 未变化%d个", result.Created, result.Updated, result.Disabled, result.Unchanged)
+}
+
+// PrintModelSyncPlan 输出同步计划，用于--dry-run
+func PrintModelSyncPlan(result *models.ModelImportResult) {
+	for _, item := range result.Items {
+		fmt.Printf("%-10s %s\n", item.Action, item.Name)
+		if item.Action == models.ImportActionUnchanged {
+			continue
+		}
+		for _, change := range item.Diff {
+			if item.Action == models.ImportActionCreate {
+				fmt.Printf("           %s: %v\n", change.Field, change.New)
+				continue
+			}
+			fmt.Printf("           %s: %v -> %v\n", change.Field, change.Old, change.New)
+		}
+	}
+	fmt.Printf("新建%d个, 更新%d个, 停用%d个, 未变化%d个\n", result.Created, result.Updated, result.Disabled, result.Unchanged)
+}
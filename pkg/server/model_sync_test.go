@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TestSyncModelsFromFileConcurrent 模拟多个副本同时启动，同一个模型文件并发同步时都成功且只新建一次
+func TestSyncModelsFromFileConcurrent(t *testing.T) {
+	dbtest.Setup(t)
+	t.Setenv("MYAPI_TEST_SYNC_KEY", "sk-test")
+
+	names := []string{"sync-a-" + uuid.New().String(), "sync-b-" + uuid.New().String()}
+	content := "models:\n"
+	for _, name := range names {
+		content += fmt.Sprintf("  - name: %s\n    endpoint: http://127.0.0.1:9/v1/chat/completions\n"+
+			"    api_key_env: MYAPI_TEST_SYNC_KEY\n    timeout: 5\n    type: chat\n", name)
+	}
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入模型文件失败: %v", err)
+	}
+
+	const n = 4
+	created := make([]int, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := SyncModelsFromFile(path, false)
+			if err == nil {
+				created[i] = result.Created
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("第%d次同步失败: %v", i, err)
+		}
+		total += created[i]
+	}
+	if total != len(names) {
+		t.Errorf("并发同步共新建%d个模型，期望%d个", total, len(names))
+	}
+}
+
+// TestSyncModelsFromFileDisabled 模型文件中enabled为false的模型在第一次同步时就以停用状态新建
+func TestSyncModelsFromFileDisabled(t *testing.T) {
+	dbtest.Setup(t)
+	t.Setenv("MYAPI_TEST_SYNC_KEY", "sk-test")
+
+	name := "sync-disabled-" + uuid.New().String()
+	content := fmt.Sprintf("models:\n  - name: %s\n    endpoint: http://127.0.0.1:9/v1/chat/completions\n"+
+		"    api_key_env: MYAPI_TEST_SYNC_KEY\n    timeout: 5\n    type: chat\n    enabled: false\n", name)
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入模型文件失败: %v", err)
+	}
+
+	result, err := SyncModelsFromFile(path, false)
+	if err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if result.Created != 1 {
+		t.Fatalf("同步新建了%d个模型，期望1个", result.Created)
+	}
+	var model models.Model
+	if err := db.GetDB().Where("name = ?", name).First(&model).Error; err != nil {
+		t.Fatalf("查询同步的模型失败: %v", err)
+	}
+	if model.Enabled {
+		t.Errorf("第一次同步enabled为false的模型后模型为启用状态")
+	}
+
+	// 再次同步时配置没有变化
+	if result, err = SyncModelsFromFile(path, false); err != nil || result.Unchanged != 1 {
+		t.Errorf("再次同步的结果为%+v(%v)，期望模型未变化", result, err)
+	}
+}
@@ -0,0 +1,406 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+	"myapi/pkg/secret"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+var (
+	// errInvalidImport 导入的模型配置不合法，整批导入不生效
+	errInvalidImport = errors.New("导入内容不合法")
+	// errImportDryRun 预览导入时用于回滚事务
+	errImportDryRun = errors.New("预览导入，回滚事务")
+)
+
+// secretKeyHeader 加密导出和导入API Key时使用的口令请求头
+const secretKeyHeader = "X-Secret-Key"
+
+// modelImportOptions 导入模型的选项
+type modelImportOptions struct {
+	DryRun     bool
+	Actor      string
+	Passphrase string // 解密api_key_encrypted使用的口令
+	// 停用未在本次导入中列出的模型
+	DisableUnlisted bool
+}
+
+// ExportModels 导出未删除的模型，format为yaml（默认）或json，secrets为omit（默认）、plain或encrypt
+func (h *ModelHandler) ExportModels(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: format 可选值为 yaml|json"))
+		return
+	}
+	secrets := c.DefaultQuery("secrets", models.SecretsOmit)
+	passphrase := c.GetHeader(secretKeyHeader)
+	switch secrets {
+	case models.SecretsOmit, models.SecretsPlain:
+	case models.SecretsEncrypt:
+		if passphrase == "" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 加密导出需要在请求头 "+secretKeyHeader+" 中提供口令"))
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: secrets 可选值为 omit|plain|encrypt"))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var modelList []models.Model
+	if err := database.Order("name ASC").Find(&modelList).Error; err != nil {
+		zap.S().Errorf("查询模型列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型列表失败: "+err.Error()))
+		return
+	}
+	names, err := modelNames(database)
+	if err != nil {
+		zap.S().Errorf("查询模型名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询模型名称失败: "+err.Error()))
+		return
+	}
+
+	var encrypter *secret.Encrypter
+	if secrets == models.SecretsEncrypt {
+		if encrypter, err = secret.NewEncrypter(passphrase); err != nil {
+			zap.S().Errorf("派生导出密钥失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "加密API Key失败: "+err.Error()))
+			return
+		}
+	}
+
+	export := models.ModelExport{
+		Version:    models.ModelExportVersion,
+		ExportedAt: time.Now(),
+		Secrets:    secrets,
+		Models:     make([]models.ModelSpec, 0, len(modelList)),
+	}
+	for i := range modelList {
+		spec := models.NewModelSpec(&modelList[i], names[modelList[i].SummarizerModelID])
+		switch secrets {
+		case models.SecretsPlain:
+			spec.APIKey = modelList[i].APIKey
+		case models.SecretsEncrypt:
+			if spec.APIKeyEncrypted, err = encrypter.Encrypt(modelList[i].APIKey); err != nil {
+				zap.S().Errorf("加密模型[%s]的API Key失败: %v", modelList[i].Name, err)
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "加密API Key失败: "+err.Error()))
+				return
+			}
+		}
+		export.Models = append(export.Models, spec)
+	}
+
+	var data []byte
+	contentType := "application/x-yaml; charset=utf-8"
+	if format == "json" {
+		data, err = json.MarshalIndent(export, "", "  ")
+		contentType = "application/json; charset=utf-8"
+	} else {
+		data, err = yaml.Marshal(export)
+	}
+	if err != nil {
+		zap.S().Errorf("序列化导出文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "导出模型失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("导出模型%d个, secrets=%s", len(export.Models), secrets)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="models.%s"`, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportModels 按名称导入模型，已存在的更新、不存在的新建，全部成功才提交；dry_run=true时只返回变更计划
+func (h *ModelHandler) ImportModels(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "读取请求体失败: "+err.Error()))
+		return
+	}
+	// JSON是YAML的子集，两种格式都按YAML解析
+	var doc models.ModelExport
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "解析导入文件失败: "+err.Error()))
+		return
+	}
+	for _, spec := range doc.Models {
+		if spec.APIKeyEnv != "" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, fmt.Sprintf("参数错误: 模型%s的 api_key_env 只能用于声明式同步", spec.Name)))
+			return
+		}
+	}
+	if doc.Version > models.ModelExportVersion {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, fmt.Sprintf("不支持的导入文件版本: %d", doc.Version)))
+		return
+	}
+	// 版本1的文件由未加盐的口令摘要加密，不再支持解密
+	if doc.Version < models.ModelExportVersion {
+		for _, spec := range doc.Models {
+			if spec.APIKeyEncrypted != "" {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, fmt.Sprintf("参数错误: 导入文件版本%d的加密API Key已不再支持，请重新导出", doc.Version)))
+				return
+			}
+		}
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	result, err := importModels(database, doc.Models, modelImportOptions{
+		DryRun:     dryRun,
+		Actor:      requestActor(c),
+		Passphrase: c.GetHeader(secretKeyHeader),
+	})
+	if err != nil {
+		if errors.Is(err, errInvalidImport) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+			return
+		}
+		writeModelChangeError(c, "导入模型", err)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(result, "导入预览"))
+		return
+	}
+	zap.S().Infof("导入模型完成: 新建%d个, 更新%d个, 未变化%d个", result.Created, result.Updated, result.Unchanged)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result, "导入成功"))
+}
+
+// importModels 在一个事务中按名称新建或更新模型，任一模型不合法时整体回滚；预览时执行后回滚，以便校验引用关系
+func importModels(database *gorm.DB, specs []models.ModelSpec, opts modelImportOptions) (*models.ModelImportResult, error) {
+	ordered, err := orderModelSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypter := secret.NewDecrypter(opts.Passphrase)
+	result := &models.ModelImportResult{DryRun: opts.DryRun, Items: make([]models.ModelImportItem, 0, len(ordered))}
+	var updated []string
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for _, spec := range ordered {
+			item, err := importModel(tx, spec, opts, decrypter)
+			if err != nil {
+				return err
+			}
+			switch item.Action {
+			case models.ImportActionCreate:
+				result.Created++
+			case models.ImportActionUpdate:
+				result.Updated++
+				updated = append(updated, item.ModelID)
+			default:
+				result.Unchanged++
+			}
+			result.Items = append(result.Items, *item)
+		}
+		if opts.DisableUnlisted {
+			items, err := disableUnlistedModels(tx, ordered, opts.Actor)
+			if err != nil {
+				return err
+			}
+			result.Disabled = len(items)
+			result.Items = append(result.Items, items...)
+		}
+		if opts.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return nil, err
+	}
+	if !opts.DryRun {
+		for _, id := range updated {
+			resetModelStatus(database, id)
+		}
+	}
+	return result, nil
+}
+
+// importModel 导入单个模型，返回变更计划
+func importModel(tx *gorm.DB, spec *models.ModelSpec, opts modelImportOptions, decrypter *secret.Decrypter) (*models.ModelImportItem, error) {
+	var existing models.Model
+	found := true
+	if err := tx.Unscoped().Where("name = ?", spec.Name).First(&existing).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		found = false
+	}
+	if found && existing.DeletedAt.Valid {
+		return nil, fmt.Errorf("%w: 模型%s已被删除，请先恢复或彻底删除", errInvalidImport, spec.Name)
+	}
+
+	apiKey := spec.APIKey
+	if spec.APIKeyEncrypted != "" {
+		if opts.Passphrase == "" {
+			return nil, fmt.Errorf("%w: 模型%s的API Key已加密，需要在请求头 %s 中提供口令", errInvalidImport, spec.Name, secretKeyHeader)
+		}
+		plain, err := decrypter.Decrypt(spec.APIKeyEncrypted)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 模型%s: %v", errInvalidImport, spec.Name, err)
+		}
+		apiKey = plain
+	}
+
+	// 未提供API Key时保留已有模型的密钥
+	model := models.Model{ModelID: uuid.New().String(), Enabled: true, Version: 1}
+	if found {
+		model = existing
+	}
+	if apiKey != "" {
+		model.APIKey = apiKey
+	}
+	if model.APIKey == "" {
+		return nil, fmt.Errorf("%w: 新建模型%s需要提供 api_key 或 api_key_encrypted", errInvalidImport, spec.Name)
+	}
+	spec.ApplyTo(&model)
+
+	model.SummarizerModelID = ""
+	if spec.Summarizer != "" {
+		var summarizer models.Model
+		if err := tx.Where("name = ?", spec.Summarizer).First(&summarizer).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: 模型%s的摘要模型%s不存在", errInvalidImport, spec.Name, spec.Summarizer)
+			}
+			return nil, err
+		}
+		model.SummarizerModelID = summarizer.ModelID
+	}
+	var before *models.Model
+	if found {
+		before = &existing
+	}
+	if msg := validateModelConfig(tx, before, &model); msg != "" {
+		return nil, fmt.Errorf("%w: 模型%s: %s", errInvalidImport, spec.Name, msg)
+	}
+
+	item := &models.ModelImportItem{Name: model.Name, ModelID: model.ModelID}
+	if !found {
+		item.Action = models.ImportActionCreate
+		item.Diff = models.DiffModels(nil, &model)
+		err := saveModelChangeAs(tx, opts.Actor, models.RevisionActionCreate, nil, &model, func(tx *gorm.DB) error {
+			if err := tx.Create(&model).Error; err != nil {
+				if db.IsDuplicateKeyError(err) {
+					return errNameConflict
+				}
+				return err
+			}
+			return nil
+		})
+		return item, err
+	}
+
+	item.Diff = models.DiffModels(&existing, &model)
+	if len(item.Diff) == 0 {
+		item.Action = models.ImportActionUnchanged
+		return item, nil
+	}
+	item.Action = models.ImportActionUpdate
+	err := saveModelChangeAs(tx, opts.Actor, models.RevisionActionUpdate, &existing, &model, func(tx *gorm.DB) error {
+		return updateModelVersioned(tx, &existing, &model)
+	})
+	return item, err
+}
+
+// disableUnlistedModels 停用名称不在specs中的已启用模型
+func disableUnlistedModels(tx *gorm.DB, specs []*models.ModelSpec, actor string) ([]models.ModelImportItem, error) {
+	query := tx.Where("enabled = ?", true)
+	if len(specs) > 0 {
+		names := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			names = append(names, spec.Name)
+		}
+		query = query.Where("name NOT IN ?", names)
+	}
+	var unlisted []models.Model
+	if err := query.Order("name ASC").Find(&unlisted).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]models.ModelImportItem, 0, len(unlisted))
+	for i := range unlisted {
+		before := unlisted[i]
+		model := before
+		model.Enabled = false
+		err := saveModelChangeAs(tx, actor, models.RevisionActionDisable, &before, &model, func(tx *gorm.DB) error {
+			return updateModelVersioned(tx, &before, &model)
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, models.ModelImportItem{
+			Name:    model.Name,
+			ModelID: model.ModelID,
+			Action:  models.ImportActionDisable,
+			Diff:    models.DiffModels(&before, &model),
+		})
+	}
+	return items, nil
+}
+
+// orderModelSpecs 校验名称并排序，使摘要模型先于引用它的模型导入
+func orderModelSpecs(specs []models.ModelSpec) ([]*models.ModelSpec, error) {
+	pending := make(map[string]*models.ModelSpec, len(specs))
+	for i := range specs {
+		if specs[i].Name == "" {
+			return nil, fmt.Errorf("%w: 第%d个模型缺少 name", errInvalidImport, i+1)
+		}
+		if _, ok := pending[specs[i].Name]; ok {
+			return nil, fmt.Errorf("%w: 模型名称%s重复", errInvalidImport, specs[i].Name)
+		}
+		pending[specs[i].Name] = &specs[i]
+	}
+
+	ordered := make([]*models.ModelSpec, 0, len(specs))
+	for len(pending) > 0 {
+		progressed := false
+		for i := range specs {
+			spec := &specs[i]
+			if _, ok := pending[spec.Name]; !ok {
+				continue
+			}
+			if _, waiting := pending[spec.Summarizer]; waiting && spec.Summarizer != spec.Name {
+				continue
+			}
+			ordered = append(ordered, spec)
+			delete(pending, spec.Name)
+			progressed = true
+		}
+		if !progressed {
+			return nil, fmt.Errorf("%w: 摘要模型存在循环引用", errInvalidImport)
+		}
+	}
+	return ordered, nil
+}
+
+// modelNames 返回所有模型（包括已删除的）ID到名称的映射
+func modelNames(database *gorm.DB) (map[string]string, error) {
+	var modelList []models.Model
+	if err := database.Unscoped().Select("model_id", "name").Find(&modelList).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(modelList))
+	for _, m := range modelList {
+		names[m.ModelID] = m.Name
+	}
+	return names, nil
+}
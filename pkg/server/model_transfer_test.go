@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TestImportDisabledModel 导入enabled为false的新模型时以停用状态写入
+func TestImportDisabledModel(t *testing.T) {
+	dbtest.Setup(t)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := NewLocalHandler(auditor)
+
+	name := "import-disabled-" + uuid.New().String()
+	doc := fmt.Sprintf("version: %d\nmodels:\n  - name: %s\n    endpoint: http://127.0.0.1:9/v1/chat/completions\n"+
+		"    api_key: sk-test\n    timeout: 5\n    type: chat\n    enabled: false\n", models.ModelExportVersion, name)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/models/import", strings.NewReader(doc)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("导入模型返回%d: %s", rec.Code, rec.Body.String())
+	}
+
+	var model models.Model
+	if err := db.GetDB().Where("name = ?", name).First(&model).Error; err != nil {
+		t.Fatalf("查询导入的模型失败: %v", err)
+	}
+	if model.Enabled {
+		t.Errorf("导入enabled为false的模型后模型为启用状态")
+	}
+}
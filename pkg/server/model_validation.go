@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"myapi/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// validateModelConfig 按模型类型校验配置，返回错误提示，合法时返回空字符串；before为修改前的模型，新建时为nil。
+// 新建或修改类型时类型会被规范化为小写并校验取值；类型未修改时保留原值，类型限制引入之前登记的模型仍可以更新、停用和回滚
+func validateModelConfig(database *gorm.DB, before, model *models.Model) string {
+	if before != nil && strings.EqualFold(strings.TrimSpace(model.Type), before.Type) {
+		model.Type = before.Type
+	} else {
+		model.Type = strings.ToLower(strings.TrimSpace(model.Type))
+		if !models.IsValidModelType(model.Type) {
+			return fmt.Sprintf("不支持的模型类型: %s，可选值为 %s", model.Type, strings.Join(models.ModelTypes(), "|"))
+		}
+	}
+	if model.Timeout <= 0 {
+		return "timeout 必须大于0"
+	}
+
+	// 向量维度只对嵌入模型有意义，其他类型忽略
+	if model.IsType(models.ModelTypeEmbedding) {
+		if model.Dimensions <= 0 {
+			return "embedding 类型的模型必须指定大于0的 dimensions"
+		}
+	} else {
+		model.Dimensions = 0
+	}
+
+	if !models.IsValidScoreNormalization(model.ScoreNormalization) {
+		return fmt.Sprintf("不支持的分数归一化方式: %s，可选值为空或 %s", model.ScoreNormalization, models.ScoreNormalizationSigmoid)
+	}
+	if !model.IsType(models.ModelTypeRerank) && model.ScoreNormalization != models.ScoreNormalizationNone {
+		return "只有 rerank 类型的模型可以设置 score_normalization"
+	}
+
+	if !model.IsType(models.ModelTypeChat) {
+		if model.HasCapabilities() {
+			return "只有 chat 类型的模型可以设置 supports_vision、supports_tools、supports_json_mode 和 supports_streaming"
+		}
+		if model.ContextWindow > 0 || model.MaxOutputTokens > 0 || model.ContextStrategy != "" || model.SummarizerModelID != "" {
+			return "只有 chat 类型的模型可以设置上下文窗口和上下文策略"
+		}
+	}
+	return validateContextConfig(database, model)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// TestUpdateLegacyModelType 类型限制引入之前登记的模型在不修改类型时仍可以更新和回滚，修改类型时才校验
+func TestUpdateLegacyModelType(t *testing.T) {
+	dbtest.Setup(t)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := NewLocalHandler(auditor)
+
+	legacy := models.Model{
+		ModelID:  uuid.New().String(),
+		Name:     "legacy-" + uuid.New().String(),
+		Endpoint: "http://127.0.0.1:9/v1/chat/completions",
+		APIKey:   "sk-test",
+		Timeout:  5,
+		Type:     "LLM",
+		Enabled:  true,
+		Version:  1,
+	}
+	if err := db.GetDB().Create(&legacy).Error; err != nil {
+		t.Fatalf("写入旧模型失败: %v", err)
+	}
+
+	send := func(method, path string, body any) (int, string) {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+		return rec.Code, rec.Body.String()
+	}
+	path := "/api/v1/models/" + legacy.ModelID
+
+	timeout := 10
+	if code, body := send(http.MethodPut, path, models.UpdateModelRequest{Timeout: &timeout}); code != http.StatusOK {
+		t.Fatalf("不修改类型时更新旧模型返回%d: %s", code, body)
+	}
+	if code, body := send(http.MethodPost, path+"/rollback", models.RollbackModelRequest{Revision: 1}); code != http.StatusOK {
+		t.Fatalf("回滚旧模型返回%d: %s", code, body)
+	}
+	var stored models.Model
+	if err := db.GetDB().Where("model_id = ?", legacy.ModelID).First(&stored).Error; err != nil {
+		t.Fatalf("查询模型失败: %v", err)
+	}
+	if stored.Type != "LLM" {
+		t.Errorf("更新后的类型为%s，期望保留原值LLM", stored.Type)
+	}
+
+	invalid := "llm2"
+	if code, body := send(http.MethodPut, path, models.UpdateModelRequest{Type: &invalid}); code != http.StatusBadRequest {
+		t.Errorf("修改为不支持的类型返回%d: %s，期望400", code, body)
+	}
+	chat := "Chat"
+	if code, body := send(http.MethodPut, path, models.UpdateModelRequest{Type: &chat}); code != http.StatusOK {
+		t.Errorf("修改为chat类型返回%d: %s，期望200", code, body)
+	}
+	if err := db.GetDB().Where("model_id = ?", legacy.ModelID).First(&stored).Error; err != nil {
+		t.Fatalf("查询模型失败: %v", err)
+	}
+	if stored.Type != models.ModelTypeChat {
+		t.Errorf("修改后的类型为%s，期望规范化为chat", stored.Type)
+	}
+}
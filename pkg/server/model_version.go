@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// errVersionConflict 模型在读取后已被其他请求修改
+	errVersionConflict = errors.New("模型已被其他请求修改，请重新获取后再试")
+	// errNameConflict 模型名称违反唯一约束
+	errNameConflict = errors.New("模型名称已存在")
+)
+
+// modelETag 返回模型当前版本对应的ETag
+func modelETag(model *models.Model) string {
+	return `"` + strconv.Itoa(model.Version) + `"`
+}
+
+// checkIfMatch 校验If-Match请求头与模型当前版本一致，未提供时不校验；不一致时直接写入412响应。
+// If-Match使用强比较（RFC 9110 §13.1.1），弱ETag（W/前缀）不会匹配
+func checkIfMatch(c *gin.Context, model *models.Model) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	current := modelETag(model)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == current {
+			return true
+		}
+	}
+	c.Header("ETag", current)
+	c.JSON(http.StatusPreconditionFailed, models.NewErrorResponse(412, "模型版本已变化，当前ETag为"+current))
+	return false
+}
+
+// updateModelVersioned 只在数据库中的版本仍为before.Version时写入after的全部字段，并将版本号加1；名称冲突时返回errNameConflict
+func updateModelVersioned(tx *gorm.DB, before, after *models.Model) error {
+	after.Version = before.Version + 1
+	result := tx.Unscoped().Model(&models.Model{}).
+		Where("model_id = ? AND version = ?", before.ModelID, before.Version).
+		Select("*").Omit("model_id", "created_at").Updates(after)
+	if result.Error != nil {
+		if db.IsDuplicateKeyError(result.Error) {
+			return errNameConflict
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	return nil
+}
+
+// writeNameConflict 写入名称冲突的409响应，名称被已删除的模型占用时提示恢复或彻底删除
+func writeNameConflict(c *gin.Context, database *gorm.DB, name string) {
+	var existingModel models.Model
+	if err := database.Unscoped().Where("name = ?", name).First(&existingModel).Error; err == nil && existingModel.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, models.NewErrorResponse(409, "模型名称已被已删除的模型占用，请恢复或彻底删除该模型"))
+		return
+	}
+	c.JSON(http.StatusConflict, models.NewErrorResponse(409, errNameConflict.Error()))
+}
+
+// writeModelChangeError 写入模型变更失败的响应，名称冲突为409，版本冲突为412
+func writeModelChangeError(c *gin.Context, action string, err error) {
+	if errors.Is(err, errVersionConflict) {
+		c.JSON(http.StatusPreconditionFailed, models.NewErrorResponse(412, err.Error()))
+		return
+	}
+	if errors.Is(err, errNameConflict) {
+		c.JSON(http.StatusConflict, models.NewErrorResponse(409, err.Error()))
+		return
+	}
+	zap.S().Errorf("%s失败: %v", action, err)
+	c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, action+"失败: "+err.Error()))
+}
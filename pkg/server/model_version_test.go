@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	model := &models.Model{Version: 3}
+	tests := []struct {
+		header string
+		match  bool
+	}{
+		{"", true},
+		{"*", true},
+		{`"3"`, true},
+		{`"2", "3"`, true},
+		{`"2"`, false},
+		{`W/"3"`, false},
+		{`W/"3", "2"`, false},
+		{`3`, false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPut, "/api/v1/models/m", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("If-Match", tt.header)
+		}
+		if got := checkIfMatch(c, model); got != tt.match {
+			t.Errorf("If-Match: %s 的结果为%v，期望%v", tt.header, got, tt.match)
+		}
+		if !tt.match && rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match: %s 返回%d，期望412", tt.header, rec.Code)
+		}
+	}
+}
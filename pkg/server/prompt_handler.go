@@ -0,0 +1,430 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+	"myapi/pkg/prompt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PromptHandler 提示词模板相关的处理器
+type PromptHandler struct{}
+
+// NewPromptHandler 创建新的提示词模板处理器
+func NewPromptHandler() *PromptHandler {
+	return &PromptHandler{}
+}
+
+// promptVersionRetries 并发更新同一个模板时版本号冲突的重试次数
+const promptVersionRetries = 3
+
+// reservedPromptNames 与提示词模板静态路由冲突的名称
+var reservedPromptNames = map[string]struct{}{
+	"create": {},
+	"get":    {},
+}
+
+// CreatePromptTemplate 创建提示词模板的第一个版本
+func (h *PromptHandler) CreatePromptTemplate(c *gin.Context) {
+	var req models.CreatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("创建提示词模板参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	// 与/prompts下的静态路由同名的模板无法通过/prompts/:name访问
+	if _, reserved := reservedPromptNames[req.Name]; reserved {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 模板名称不能为 "+req.Name))
+		return
+	}
+
+	pt := models.PromptTemplate{
+		TemplateID:  uuid.New().String(),
+		Name:        req.Name,
+		Version:     1,
+		Active:      true,
+		Description: req.Description,
+		Body:        req.Body,
+		Variables:   req.Variables,
+		ModelID:     req.ModelID,
+		Model:       req.Model,
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	if msg := validatePromptTemplate(database, &pt); msg != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+msg))
+		return
+	}
+
+	// 模板已存在时第一个版本违反(name, version)唯一约束
+	if err := database.Create(&pt).Error; err != nil {
+		if db.IsDuplicateKeyError(err) {
+			zap.S().Warnf("尝试创建重复的提示词模板: %s", pt.Name)
+			c.JSON(http.StatusConflict, models.NewErrorResponse(409, "提示词模板已存在"))
+			return
+		}
+		zap.S().Errorf("创建提示词模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "创建提示词模板失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功创建提示词模板: %s", pt.Name)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(pt, "成功创建提示词模板"))
+}
+
+// GetPromptTemplates 获取提示词模板列表，每个模板只返回当前生效的版本
+func (h *PromptHandler) GetPromptTemplates(c *gin.Context) {
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var list []models.PromptTemplate
+	var total int64
+	query := database.Model(&models.PromptTemplate{}).Where("active = ?", true)
+	if err := query.Count(&total).Error; err != nil {
+		zap.S().Errorf("查询提示词模板总数失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询提示词模板总数失败: "+err.Error()))
+		return
+	}
+	if err := query.Order("name ASC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&list).Error; err != nil {
+		zap.S().Errorf("查询提示词模板列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询提示词模板列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"list":      list,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "查询提示词模板列表成功"))
+}
+
+// GetPromptTemplate 获取提示词模板，可通过 version 参数指定版本
+func (h *PromptHandler) GetPromptTemplate(c *gin.Context) {
+	version, _ := strconv.Atoi(c.DefaultQuery("version", "0"))
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	pt, ok := findPromptTemplate(c, database, c.Param("name"), version)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(pt, "查询成功"))
+}
+
+// GetPromptTemplateVersions 获取提示词模板的全部版本
+func (h *PromptHandler) GetPromptTemplateVersions(c *gin.Context) {
+	name := c.Param("name")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	var list []models.PromptTemplate
+	if err := database.Where("name = ?", name).Order("version DESC").Find(&list).Error; err != nil {
+		zap.S().Errorf("查询提示词模板版本失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询提示词模板版本失败: "+err.Error()))
+		return
+	}
+	if len(list) == 0 {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "提示词模板不存在"))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(list, "查询成功"))
+}
+
+// UpdatePromptTemplate 基于当前生效的版本创建新版本，新版本立即生效
+func (h *PromptHandler) UpdatePromptTemplate(c *gin.Context) {
+	name := c.Param("name")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	current, ok := findPromptTemplate(c, database, name, 0)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("更新提示词模板参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	pt := current
+	pt.TemplateID = uuid.New().String()
+	pt.CreatedAt = time.Time{}
+	if req.Description != nil {
+		pt.Description = *req.Description
+	}
+	if req.Body != nil {
+		pt.Body = *req.Body
+	}
+	if req.Variables != nil {
+		pt.Variables = *req.Variables
+	}
+	if req.ModelID != nil {
+		pt.ModelID = *req.ModelID
+	}
+	if req.Model != nil {
+		pt.Model = *req.Model
+	}
+	if msg := validatePromptTemplate(database, &pt); msg != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+msg))
+		return
+	}
+
+	// 并发更新时新版本号违反(name, version)唯一约束，整个事务重试
+	var err error
+	for attempt := 1; attempt <= promptVersionRetries; attempt++ {
+		err = database.Transaction(func(tx *gorm.DB) error {
+			var latest int
+			if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).
+				Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).Update("active", false).Error; err != nil {
+				return err
+			}
+			pt.Version = latest + 1
+			pt.Active = true
+			return tx.Create(&pt).Error
+		})
+		if !db.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		if db.IsDuplicateKeyError(err) {
+			zap.S().Warnf("更新提示词模板%s时版本号冲突", name)
+			c.JSON(http.StatusConflict, models.NewErrorResponse(409, "提示词模板正在被其他请求更新，请重试"))
+			return
+		}
+		zap.S().Errorf("更新提示词模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "更新提示词模板失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功更新提示词模板: %s, 版本: %d", pt.Name, pt.Version)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(pt, "成功更新提示词模板"))
+}
+
+// RollbackPromptTemplate 将指定的历史版本设为生效版本
+func (h *PromptHandler) RollbackPromptTemplate(c *gin.Context) {
+	name := c.Param("name")
+
+	var req models.RollbackPromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	pt, ok := findPromptTemplate(c, database, name, req.Version)
+	if !ok {
+		return
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).Update("active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&pt).Update("active", true).Error
+	})
+	if err != nil {
+		zap.S().Errorf("回滚提示词模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "回滚提示词模板失败: "+err.Error()))
+		return
+	}
+
+	zap.S().Infof("成功回滚提示词模板: %s, 版本: %d", pt.Name, pt.Version)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(pt, "成功回滚提示词模板"))
+}
+
+// DeletePromptTemplate 删除提示词模板的全部版本
+func (h *PromptHandler) DeletePromptTemplate(c *gin.Context) {
+	name := c.Param("name")
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	result := database.Where("name = ?", name).Delete(&models.PromptTemplate{})
+	if result.Error != nil {
+		zap.S().Errorf("删除提示词模板失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除提示词模板失败: "+result.Error.Error()))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "提示词模板不存在"))
+		return
+	}
+
+	zap.S().Infof("成功删除提示词模板: %s", name)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"name": name, "versions": result.RowsAffected}, "成功删除提示词模板"))
+}
+
+// RenderPromptTemplate 使用变量渲染提示词模板
+func (h *PromptHandler) RenderPromptTemplate(c *gin.Context) {
+	var req models.RenderPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	pt, ok := findPromptTemplate(c, database, c.Param("name"), req.Version)
+	if !ok {
+		return
+	}
+	content, err := prompt.Render(&pt, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"name":    pt.Name,
+		"version": pt.Version,
+		"content": content,
+	}, "渲染成功"))
+}
+
+// ChatWithPromptTemplate 渲染提示词模板作为系统提示词，并转发到大模型
+func (h *PromptHandler) ChatWithPromptTemplate(c *gin.Context) {
+	var req models.PromptChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	pt, ok := findPromptTemplate(c, database, c.Param("name"), req.Version)
+	if !ok {
+		return
+	}
+	content, err := prompt.Render(&pt, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	modelID, upstreamModel := pt.ModelID, pt.Model
+	if req.ModelID != "" {
+		modelID = req.ModelID
+	}
+	if req.Model != "" {
+		upstreamModel = req.Model
+	}
+	if modelID == "" || upstreamModel == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: 模板未绑定模型，需要指定 model_id 和 model"))
+		return
+	}
+
+	model, ok := findCallableModel(c, database.Where("model_id = ?", modelID))
+	if !ok {
+		return
+	}
+
+	chatReq := models.ChatRequest{
+		Model:    upstreamModel,
+		Messages: append([]models.ChatMessage{{Role: "system", Content: content}}, req.Messages...),
+	}
+	if err := llm.CheckChatRequest(&model, &chatReq); err != nil {
+		writeUpstreamCallError(c, err)
+		return
+	}
+	messages, err := llm.FitContext(ctx, &model, chatReq.Model, chatReq.Messages, models.ModelLoader(database))
+	if err != nil {
+		var tooLong *llm.ContextTooLongError
+		if errors.As(err, &tooLong) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "处理上下文失败: "+err.Error()))
+		}
+		return
+	}
+	chatReq.Messages = messages
+
+	resp, err := llm.Do(ctx, &model, chatReq)
+	if err != nil {
+		writeUpstreamCallError(c, err)
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, _ := io.ReadAll(resp.Body)
+
+	// 直接返回大模型响应
+	c.Data(resp.StatusCode, "application/json", body)
+}
+
+// findPromptTemplate 查询提示词模板，version为0时查询当前生效的版本；不存在或查询失败时直接写入错误响应
+func findPromptTemplate(c *gin.Context, database *gorm.DB, name string, version int) (models.PromptTemplate, bool) {
+	var pt models.PromptTemplate
+	query := database.Where("name = ?", name)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.First(&pt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.S().Warnf("请求的提示词模板不存在: %s, 版本: %d", name, version)
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "提示词模板不存在"))
+		} else {
+			zap.S().Errorf("查询提示词模板失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "查询提示词模板失败: "+err.Error()))
+		}
+		return pt, false
+	}
+	return pt, true
+}
+
+// validatePromptTemplate 校验模板语法、变量声明和绑定的模型，返回错误提示，合法时返回空字符串
+func validatePromptTemplate(database *gorm.DB, pt *models.PromptTemplate) string {
+	if _, err := prompt.Parse(pt.Name, pt.Body); err != nil {
+		return err.Error()
+	}
+	if err := prompt.ValidateVariables(pt.Variables); err != nil {
+		return err.Error()
+	}
+	if pt.ModelID != "" {
+		var model models.Model
+		if err := database.Where("model_id = ?", pt.ModelID).First(&model).Error; err != nil {
+			return "绑定的模型不存在"
+		}
+	}
+	return ""
+}
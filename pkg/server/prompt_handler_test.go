@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"myapi/pkg/audit"
+	"myapi/pkg/db"
+	"myapi/pkg/db/dbtest"
+	"myapi/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// concurrentRequests 同时发送n个相同的请求，返回每个请求的状态码和响应体
+func concurrentRequests(handler http.Handler, n int, method, target string, body []byte) ([]int, []string) {
+	codes := make([]int, n)
+	bodies := make([]string, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, target, bytes.NewReader(body)))
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return codes, bodies
+}
+
+// TestPromptTemplateConcurrentWrites 并发创建同名模板只有一个成功，并发更新生成互不相同的版本且只有一个生效
+func TestPromptTemplateConcurrentWrites(t *testing.T) {
+	dbtest.Setup(t)
+	auditor, err := audit.NewLogger(nil)
+	if err != nil {
+		t.Fatalf("创建审计日志记录器失败: %v", err)
+	}
+	handler := NewLocalHandler(auditor)
+
+	const n = 8
+	name := "concurrent-" + uuid.New().String()
+	body, _ := json.Marshal(models.CreatePromptTemplateRequest{Name: name, Body: "你好"})
+	codes, bodies := concurrentRequests(handler, n, http.MethodPost, "/api/v1/prompts/create", body)
+	counts := make(map[int]int)
+	for i, code := range codes {
+		counts[code]++
+		if code != http.StatusOK && code != http.StatusConflict {
+			t.Errorf("第%d个创建请求返回%d: %s", i, code, bodies[i])
+		}
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != n-1 {
+		t.Errorf("创建请求的状态码统计为%v，期望1个200和%d个409", counts, n-1)
+	}
+
+	newBody := "你好，{{.name}}"
+	body, _ = json.Marshal(models.UpdatePromptTemplateRequest{Body: &newBody})
+	codes, bodies = concurrentRequests(handler, n, http.MethodPut, "/api/v1/prompts/"+name, body)
+	succeeded := 0
+	for i, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else if code != http.StatusConflict {
+			t.Errorf("第%d个更新请求返回%d: %s", i, code, bodies[i])
+		}
+	}
+
+	var versions []models.PromptTemplate
+	if err := db.GetDB().Where("name = ?", name).Order("version ASC").Find(&versions).Error; err != nil {
+		t.Fatalf("查询模板版本失败: %v", err)
+	}
+	if len(versions) != succeeded+1 {
+		t.Errorf("模板共有%d个版本，期望%d个", len(versions), succeeded+1)
+	}
+	active := 0
+	for i, pt := range versions {
+		if pt.Version != i+1 {
+			t.Errorf("第%d个版本的版本号为%d", i+1, pt.Version)
+		}
+		if pt.Active {
+			active++
+		}
+	}
+	if active != 1 || !versions[len(versions)-1].Active {
+		t.Errorf("生效的版本有%d个，期望只有最新版本生效", active)
+	}
+}
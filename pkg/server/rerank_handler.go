@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"myapi/pkg/db"
+	"myapi/pkg/llm"
+	"myapi/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RerankHandler 重排序相关的处理器
+type RerankHandler struct{}
+
+// NewRerankHandler 创建新的重排序处理器
+func NewRerankHandler() *RerankHandler {
+	return &RerankHandler{}
+}
+
+// Rerank 按注册的rerank模型对文档重排序，请求和响应兼容Cohere/Jina
+func (h *RerankHandler) Rerank(c *gin.Context) {
+	var req models.RerankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.S().Errorf("重排序参数绑定错误: %v", err)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "参数错误: "+err.Error()))
+		return
+	}
+
+	ctx := context.Background()
+	database := db.GetDBWithContext(ctx)
+
+	model, ok := findCallableModel(c, database.Where("name = ? OR model_id = ?", req.Model, req.Model))
+	if !ok {
+		return
+	}
+	if !isRerankModel(&model) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "模型"+model.Name+"不是rerank类型的模型"))
+		return
+	}
+
+	documents := make([]string, len(req.Documents))
+	for i, d := range req.Documents {
+		documents[i] = d.Text
+	}
+	results, usage, err := llm.Rerank(ctx, &model, "", req.Query, documents, req.TopN)
+	if err != nil {
+		if status, ok := llm.RejectionStatus(err); ok {
+			c.JSON(status, models.NewErrorResponse(status, err.Error()))
+			return
+		}
+		zap.S().Errorf("模型[%s]重排序失败: %v", model.Name, err)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(502, err.Error()))
+		return
+	}
+	if req.ReturnDocuments {
+		for i := range results {
+			results[i].Document = &req.Documents[results[i].Index]
+		}
+	}
+
+	c.JSON(http.StatusOK, models.RerankResponse{
+		Model:   model.Name,
+		Results: results,
+		Usage:   models.RerankUsage{TotalTokens: usage},
+	})
+}
+
+// isRerankModel 判断模型是否为重排序模型
+func isRerankModel(model *models.Model) bool {
+	return model.IsType(models.ModelTypeRerank)
+}
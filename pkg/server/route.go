@@ -1,25 +1,90 @@
 package server
 
 import (
+	"myapi/pkg/audit"
+	"myapi/pkg/metrics"
+	"myapi/pkg/vectorstore"
+
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter(engine *gin.Engine) {
+func InitRouter(engine *gin.Engine, store vectorstore.Store, auditor *audit.Logger) {
 	// 创建模型处理器
 	modelHandler := NewModelHandler()
+	conversationHandler := NewConversationHandler()
+	promptHandler := NewPromptHandler()
+	kbHandler := NewKnowledgeBaseHandler(store)
+	rerankHandler := NewRerankHandler()
+	auditHandler := NewAuditHandler()
+
+	// 运维路由
+	engine.GET("/version", GetVersion)                   // 构建版本信息
+	engine.GET("/metrics", gin.WrapH(metrics.Handler())) // Prometheus 指标
+
+	// 兼容 Cohere/Jina 的重排序接口
+	engine.POST("/v1/rerank", rerankHandler.Rerank) // 文档重排序
 
 	// API路由组
-	api := engine.Group("/api/v1")
+	api := engine.Group("/api/v1", auditMiddleware(auditor))
 	{
+		api.GET("/audit/logs", auditHandler.GetAuditLogs) // 查询审计日志
+
 		// 模型管理路由
 		models := api.Group("/models")
 		{
-			models.POST("/create", modelHandler.CreateModel)     // 创建模型
-			models.GET("/get", modelHandler.GetModels)           // 获取模型列表
-			models.GET("/:id", modelHandler.GetModel)            // 获取单个模型
-			models.PUT("/:id", modelHandler.UpdateModel)         // 更新模型
-			models.DELETE("/:id", modelHandler.DeleteModel)      // 删除模型
-			models.POST("/chat/:id", modelHandler.ChatWithModel) // 大模型对话
+			models.POST("/create", modelHandler.CreateModel)             // 创建模型
+			models.GET("/get", modelHandler.GetModels)                   // 获取模型列表
+			models.GET("/export", modelHandler.ExportModels)             // 导出模型
+			models.POST("/import", modelHandler.ImportModels)            // 导入模型
+			models.GET("/:id", modelHandler.GetModel)                    // 获取单个模型
+			models.PUT("/:id", modelHandler.UpdateModel)                 // 更新模型
+			models.DELETE("/:id", modelHandler.DeleteModel)              // 删除模型
+			models.POST("/chat/:id", modelHandler.ChatWithModel)         // 大模型对话
+			models.POST("/:id/tokenize", modelHandler.TokenizeChat)      // 计算token数
+			models.POST("/:id/test", modelHandler.TestModel)             // 测试连通性
+			models.POST("/:id/disable", modelHandler.DisableModel)       // 停用模型
+			models.POST("/:id/enable", modelHandler.EnableModel)         // 启用模型
+			models.POST("/:id/restore", modelHandler.RestoreModel)       // 恢复已删除的模型
+			models.DELETE("/:id/purge", modelHandler.PurgeModel)         // 彻底删除模型
+			models.GET("/:id/revisions", modelHandler.GetModelRevisions) // 获取配置修订记录
+			models.POST("/:id/rollback", modelHandler.RollbackModel)     // 回滚到指定修订
+		}
+
+		// 对话管理路由
+		conversations := api.Group("/conversations")
+		{
+			conversations.POST("/create", conversationHandler.CreateConversation) // 创建对话
+			conversations.GET("/get", conversationHandler.GetConversations)       // 获取对话列表
+			conversations.GET("/:id", conversationHandler.GetConversation)        // 获取单个对话及消息
+			conversations.DELETE("/:id", conversationHandler.DeleteConversation)  // 删除对话
+			conversations.POST("/:id/messages", conversationHandler.SendMessage)  // 向对话发送消息
+		}
+
+		// 提示词模板路由
+		prompts := api.Group("/prompts")
+		{
+			prompts.POST("/create", promptHandler.CreatePromptTemplate)             // 创建模板
+			prompts.GET("/get", promptHandler.GetPromptTemplates)                   // 获取模板列表
+			prompts.GET("/:name", promptHandler.GetPromptTemplate)                  // 获取模板，可指定版本
+			prompts.GET("/:name/versions", promptHandler.GetPromptTemplateVersions) // 获取模板全部版本
+			prompts.PUT("/:name", promptHandler.UpdatePromptTemplate)               // 更新模板，生成新版本
+			prompts.POST("/:name/rollback", promptHandler.RollbackPromptTemplate)   // 回滚到指定版本
+			prompts.DELETE("/:name", promptHandler.DeletePromptTemplate)            // 删除模板全部版本
+			prompts.POST("/:name/render", promptHandler.RenderPromptTemplate)       // 渲染模板
+			prompts.POST("/:name/chat", promptHandler.ChatWithPromptTemplate)       // 渲染模板并发起对话
+		}
+
+		// 知识库路由
+		knowledgeBases := api.Group("/kb")
+		{
+			knowledgeBases.POST("/create", kbHandler.CreateKnowledgeBase)             // 创建知识库
+			knowledgeBases.GET("/get", kbHandler.GetKnowledgeBases)                   // 获取知识库列表
+			knowledgeBases.GET("/:id", kbHandler.GetKnowledgeBase)                    // 获取知识库及文档
+			knowledgeBases.DELETE("/:id", kbHandler.DeleteKnowledgeBase)              // 删除知识库
+			knowledgeBases.POST("/:id/documents", kbHandler.AddDocument)              // 上传文档
+			knowledgeBases.DELETE("/:id/documents/:doc_id", kbHandler.DeleteDocument) // 删除文档
+			knowledgeBases.POST("/:id/search", kbHandler.SearchKnowledgeBase)         // 知识库检索
+			knowledgeBases.POST("/:id/query", kbHandler.QueryKnowledgeBase)           // 知识库问答
 		}
 	}
 }
@@ -0,0 +1,14 @@
+package server
+
+import (
+	"net/http"
+
+	"myapi/pkg/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersion 返回当前服务的构建版本信息
+func GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, util.GetVersion())
+}
@@ -0,0 +1,133 @@
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// 支持的 BPE 编码，与 OpenAI tiktoken 的编码一致
+const (
+	O200KBase  = "o200k_base"
+	CL100KBase = "cl100k_base"
+	P50KBase   = "p50k_base"
+	R50KBase   = "r50k_base"
+
+	DefaultEncoding = CL100KBase
+)
+
+const (
+	// TokensPerMessage 每条消息的格式开销（role、分隔符等）
+	TokensPerMessage = 3
+	// TokensPerReply 回复的起始开销
+	TokensPerReply = 3
+)
+
+var (
+	encodings = []string{O200KBase, CL100KBase, P50KBase, R50KBase}
+	cache     sync.Map
+)
+
+func init() {
+	// 词表已编译进二进制，无需联网下载
+	tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+}
+
+// Tokenizer 指定编码的分词器
+type Tokenizer struct {
+	encoding string
+	tk       *tiktoken.Tiktoken
+}
+
+// Encodings 返回支持的编码名称
+func Encodings() []string {
+	return append([]string{}, encodings...)
+}
+
+// IsValidEncoding 判断编码名称是否受支持
+func IsValidEncoding(encoding string) bool {
+	for _, e := range encodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Get 返回指定编码的分词器，同一编码只加载一次词表
+func Get(encoding string) (*Tokenizer, error) {
+	if t, ok := cache.Load(encoding); ok {
+		return t.(*Tokenizer), nil
+	}
+	if !IsValidEncoding(encoding) {
+		return nil, errors.Errorf("不支持的编码: %s", encoding)
+	}
+	tk, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, errors.Wrapf(err, "加载编码%s失败", encoding)
+	}
+	t, _ := cache.LoadOrStore(encoding, &Tokenizer{encoding: encoding, tk: tk})
+	return t.(*Tokenizer), nil
+}
+
+// EncodingForModelName 根据上游模型名称推断编码，无法识别时返回默认编码
+func EncodingForModelName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[name]; ok && IsValidEncoding(encoding) {
+		return encoding
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(name, prefix) && IsValidEncoding(encoding) {
+			return encoding
+		}
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(name, prefix) {
+			return O200KBase
+		}
+	}
+	return DefaultEncoding
+}
+
+// ForModel 返回模型使用的编码：优先使用模型配置的编码，其次按上游模型名称和注册名称推断
+func ForModel(model *models.Model, upstreamModel string) string {
+	if model.Tokenizer != "" {
+		return model.Tokenizer
+	}
+	if upstreamModel != "" {
+		return EncodingForModelName(upstreamModel)
+	}
+	return EncodingForModelName(model.Name)
+}
+
+// Encoding 返回分词器的编码名称
+func (t *Tokenizer) Encoding() string {
+	return t.encoding
+}
+
+// Encode 将文本编码为token，特殊token按普通文本处理
+func (t *Tokenizer) Encode(text string) []int {
+	return t.tk.EncodeOrdinary(text)
+}
+
+// Count 计算文本的token数
+func (t *Tokenizer) Count(text string) int {
+	return len(t.Encode(text))
+}
+
+// CountMessages 计算一组对话消息的token数，返回总数和每条消息的token数
+func (t *Tokenizer) CountMessages(messages []models.ChatMessage) (int, []int) {
+	total := TokensPerReply
+	perMessage := make([]int, 0, len(messages))
+	for _, m := range messages {
+		n := TokensPerMessage + t.Count(m.Role) + t.Count(m.Content)
+		perMessage = append(perMessage, n)
+		total += n
+	}
+	return total, perMessage
+}
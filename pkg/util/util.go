@@ -16,3 +16,12 @@ func IsValidPort[T int | int32 | uint | uint32 | uint64 | int64 | string](port T
     }
     return errors.Errorf("%d不是一个合格的[0-65535]端口", p)
 }
+
+// TruncateRunes 按字符截断字符串，最多保留n个字符
+func TruncateRunes(s string, n int) string {
+    runes := []rune(s)
+    if len(runes) <= n {
+        return s
+    }
+    return string(runes[:n])
+}
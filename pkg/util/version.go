@@ -14,14 +14,14 @@ var (
 )
 
 type Version struct {
-    Version      string `json:"version" protobuf:"bytes,1,opt,name=version"`
-    BuildDate    string `json:"buildDate" protobuf:"bytes,2,opt,name=buildDate"`
-    GitCommit    string `json:"gitCommit" protobuf:"bytes,3,opt,name=gitCommit"`
-    GitTag       string `json:"gitTag" protobuf:"bytes,4,opt,name=gitTag"`
-    GitTreeState string `json:"gitTreeState" protobuf:"bytes,5,opt,name=gitTreeState"`
-    GoVersion    string `json:"goVersion" protobuf:"bytes,6,opt,name=goVersion"`
-    Compiler     string `json:"compiler" protobuf:"bytes,7,opt,name=compiler"`
-    Platform     string `json:"platform" protobuf:"bytes,8,opt,name=platform"`
+    Version      string `json:"version" yaml:"version" protobuf:"bytes,1,opt,name=version"`
+    BuildDate    string `json:"buildDate" yaml:"buildDate" protobuf:"bytes,2,opt,name=buildDate"`
+    GitCommit    string `json:"gitCommit" yaml:"gitCommit" protobuf:"bytes,3,opt,name=gitCommit"`
+    GitTag       string `json:"gitTag" yaml:"gitTag" protobuf:"bytes,4,opt,name=gitTag"`
+    GitTreeState string `json:"gitTreeState" yaml:"gitTreeState" protobuf:"bytes,5,opt,name=gitTreeState"`
+    GoVersion    string `json:"goVersion" yaml:"goVersion" protobuf:"bytes,6,opt,name=goVersion"`
+    Compiler     string `json:"compiler" yaml:"compiler" protobuf:"bytes,7,opt,name=compiler"`
+    Platform     string `json:"platform" yaml:"platform" protobuf:"bytes,8,opt,name=platform"`
 }
 
 // GetVersion returns the version information
@@ -0,0 +1,142 @@
+package vectorstore
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type memoryCollection struct {
+	dim     int
+	records map[string]Record
+}
+
+// MemoryStore 内存中的向量存储，逐条计算相似度，数据不会持久化
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]*memoryCollection
+}
+
+// NewMemoryStore 创建内存向量存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		collections: make(map[string]*memoryCollection),
+	}
+}
+
+func (s *MemoryStore) CreateCollection(_ context.Context, collection string, dim int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if coll, ok := s.collections[collection]; ok {
+		if coll.dim != dim {
+			return errors.Wrapf(ErrDimensionMismatch, "集合%s的向量维度为%d，请求的维度为%d", collection, coll.dim, dim)
+		}
+		return nil
+	}
+	s.collections[collection] = &memoryCollection{dim: dim, records: make(map[string]Record)}
+	return nil
+}
+
+func (s *MemoryStore) DropCollection(_ context.Context, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.collections, collection)
+	return nil
+}
+
+func (s *MemoryStore) Upsert(_ context.Context, collection string, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coll, ok := s.collections[collection]
+	if !ok {
+		return errors.Wrapf(ErrCollectionNotFound, "集合: %s", collection)
+	}
+	if err := checkVectors(collection, records, coll.dim); err != nil {
+		return err
+	}
+	for _, r := range records {
+		coll.records[r.ID] = Record{
+			ID:       r.ID,
+			Content:  r.Content,
+			Metadata: maps.Clone(r.Metadata),
+			Vector:   normalize(r.Vector),
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, collection string, filter Filter) error {
+	if len(filter) == 0 {
+		return errors.Wrap(ErrInvalidFilter, "删除记录时必须指定过滤条件")
+	}
+	if _, err := validateFilter(filter); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	coll, ok := s.collections[collection]
+	if !ok {
+		return nil
+	}
+	for id, r := range coll.records {
+		if matchFilter(r.Metadata, filter) {
+			delete(coll.records, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Search(_ context.Context, collection string, vector []float32, topK int, filter Filter) ([]Result, error) {
+	if _, err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	coll, ok := s.collections[collection]
+	if !ok {
+		return nil, nil
+	}
+	if len(vector) != coll.dim {
+		return nil, errors.Wrapf(ErrDimensionMismatch, "查询向量维度为%d，集合%s的维度为%d", len(vector), collection, coll.dim)
+	}
+
+	query := normalize(vector)
+	results := make([]Result, 0, len(coll.records))
+	for _, r := range coll.records {
+		if !matchFilter(r.Metadata, filter) {
+			continue
+		}
+		results = append(results, Result{
+			ID:       r.ID,
+			Content:  r.Content,
+			Metadata: maps.Clone(r.Metadata),
+			Score:    dot(query, r.Vector),
+		})
+	}
+	return topResults(results, topK), nil
+}
+
+func (s *MemoryStore) Count(_ context.Context, collection string, filter Filter) (int64, error) {
+	if _, err := validateFilter(filter); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	coll, ok := s.collections[collection]
+	if !ok {
+		return 0, nil
+	}
+	var count int64
+	for _, r := range coll.records {
+		if matchFilter(r.Metadata, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
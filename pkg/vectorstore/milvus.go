@@ -0,0 +1,308 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"myapi/config"
+	"myapi/pkg/milvus"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/pkg/errors"
+)
+
+// Milvus集合的字段，元数据保存在JSON字段中
+const (
+	milvusFieldID       = "id"
+	milvusFieldContent  = "content"
+	milvusFieldMetadata = "metadata"
+	milvusFieldVector   = "vector"
+
+	milvusIDMaxLength      = 512
+	milvusContentMaxLength = 65535
+)
+
+// MilvusStore 基于Milvus的向量存储，首次使用时才连接Milvus
+type MilvusStore struct {
+	cfg *config.MilvusConfig
+
+	mu   sync.Mutex
+	cli  client.Client
+	dims map[string]int
+}
+
+// NewMilvusStore 创建Milvus向量存储
+func NewMilvusStore(cfg *config.MilvusConfig) *MilvusStore {
+	return &MilvusStore{
+		cfg:  cfg,
+		dims: make(map[string]int),
+	}
+}
+
+func (s *MilvusStore) CreateCollection(ctx context.Context, collection string, dim int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists, err := s.describe(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if current != dim {
+			return errors.Wrapf(ErrDimensionMismatch, "集合%s的向量维度为%d，请求的维度为%d", collection, current, dim)
+		}
+		return nil
+	}
+
+	schema := entity.NewSchema().WithName(collection).WithDescription("向量存储").
+		WithField(entity.NewField().WithName(milvusFieldID).WithDataType(entity.FieldTypeVarChar).WithMaxLength(milvusIDMaxLength).WithIsPrimaryKey(true)).
+		WithField(entity.NewField().WithName(milvusFieldContent).WithDataType(entity.FieldTypeVarChar).WithMaxLength(milvusContentMaxLength)).
+		WithField(entity.NewField().WithName(milvusFieldMetadata).WithDataType(entity.FieldTypeJSON)).
+		WithField(entity.NewField().WithName(milvusFieldVector).WithDataType(entity.FieldTypeFloatVector).WithDim(int64(dim)))
+	if err := s.cli.CreateCollection(ctx, schema, entity.DefaultShardNumber); err != nil {
+		return errors.Wrapf(err, "创建集合%s失败", collection)
+	}
+	idx, err := entity.NewIndexAUTOINDEX(entity.COSINE)
+	if err != nil {
+		return err
+	}
+	if err := s.cli.CreateIndex(ctx, collection, milvusFieldVector, idx, false); err != nil {
+		return errors.Wrapf(err, "创建集合%s的向量索引失败", collection)
+	}
+	if err := s.cli.LoadCollection(ctx, collection, false); err != nil {
+		return errors.Wrapf(err, "加载集合%s失败", collection)
+	}
+	s.dims[collection] = dim
+	return nil
+}
+
+func (s *MilvusStore) DropCollection(ctx context.Context, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists, err := s.describe(ctx, collection)
+	if err != nil || !exists {
+		return err
+	}
+	if err := s.cli.DropCollection(ctx, collection); err != nil {
+		return errors.Wrapf(err, "删除集合%s失败", collection)
+	}
+	delete(s.dims, collection)
+	return nil
+}
+
+func (s *MilvusStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	cli, dim, exists, err := s.collection(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Wrapf(ErrCollectionNotFound, "集合: %s", collection)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if err := checkVectors(collection, records, dim); err != nil {
+		return err
+	}
+
+	ids := make([]string, len(records))
+	contents := make([]string, len(records))
+	metadata := make([][]byte, len(records))
+	vectors := make([][]float32, len(records))
+	for i, r := range records {
+		meta := r.Metadata
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return errors.Wrapf(err, "记录[%s]的元数据序列化失败", r.ID)
+		}
+		ids[i] = r.ID
+		contents[i] = truncateBytes(r.Content, milvusContentMaxLength)
+		metadata[i] = data
+		vectors[i] = r.Vector
+	}
+	if _, err := cli.Upsert(ctx, collection, "",
+		entity.NewColumnVarChar(milvusFieldID, ids),
+		entity.NewColumnVarChar(milvusFieldContent, contents),
+		entity.NewColumnJSONBytes(milvusFieldMetadata, metadata),
+		entity.NewColumnFloatVector(milvusFieldVector, dim, vectors),
+	); err != nil {
+		return errors.Wrapf(err, "写入集合%s失败", collection)
+	}
+	return nil
+}
+
+func (s *MilvusStore) Delete(ctx context.Context, collection string, filter Filter) error {
+	if len(filter) == 0 {
+		return errors.Wrap(ErrInvalidFilter, "删除记录时必须指定过滤条件")
+	}
+	expr, err := milvusExpr(filter)
+	if err != nil {
+		return err
+	}
+	cli, _, exists, err := s.collection(ctx, collection)
+	if err != nil || !exists {
+		return err
+	}
+	if err := cli.Delete(ctx, collection, "", expr); err != nil {
+		return errors.Wrapf(err, "删除集合%s的记录失败", collection)
+	}
+	return nil
+}
+
+func (s *MilvusStore) Search(ctx context.Context, collection string, vector []float32, topK int, filter Filter) ([]Result, error) {
+	expr, err := milvusExpr(filter)
+	if err != nil {
+		return nil, err
+	}
+	cli, dim, exists, err := s.collection(ctx, collection)
+	if err != nil || !exists {
+		return nil, err
+	}
+	if len(vector) != dim {
+		return nil, errors.Wrapf(ErrDimensionMismatch, "查询向量维度为%d，集合%s的维度为%d", len(vector), collection, dim)
+	}
+	sp, err := entity.NewIndexAUTOINDEXSearchParam(1)
+	if err != nil {
+		return nil, err
+	}
+	searchResults, err := cli.Search(ctx, collection, nil, expr,
+		[]string{milvusFieldContent, milvusFieldMetadata},
+		[]entity.Vector{entity.FloatVector(vector)}, milvusFieldVector, entity.COSINE, topK, sp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "检索集合%s失败", collection)
+	}
+
+	var results []Result
+	for _, sr := range searchResults {
+		if sr.Err != nil {
+			return nil, errors.Wrapf(sr.Err, "检索集合%s失败", collection)
+		}
+		for i := 0; i < sr.ResultCount; i++ {
+			result := Result{Score: sr.Scores[i]}
+			result.ID, _ = sr.IDs.GetAsString(i)
+			result.Content, _ = sr.Fields.GetColumn(milvusFieldContent).GetAsString(i)
+			if raw, err := sr.Fields.GetColumn(milvusFieldMetadata).Get(i); err == nil {
+				if data, ok := raw.([]byte); ok {
+					_ = json.Unmarshal(data, &result.Metadata)
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (s *MilvusStore) Count(ctx context.Context, collection string, filter Filter) (int64, error) {
+	expr, err := milvusExpr(filter)
+	if err != nil {
+		return 0, err
+	}
+	cli, _, exists, err := s.collection(ctx, collection)
+	if err != nil || !exists {
+		return 0, err
+	}
+	rs, err := cli.Query(ctx, collection, nil, expr, []string{"count(*)"})
+	if err != nil {
+		return 0, errors.Wrapf(err, "统计集合%s的记录数失败", collection)
+	}
+	column := rs.GetColumn("count(*)")
+	if column == nil || column.Len() == 0 {
+		return 0, nil
+	}
+	return column.GetAsInt64(0)
+}
+
+func (s *MilvusStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cli == nil {
+		return nil
+	}
+	err := s.cli.Close()
+	s.cli = nil
+	s.dims = make(map[string]int)
+	return err
+}
+
+// collection 返回客户端和集合的向量维度
+func (s *MilvusStore) collection(ctx context.Context, collection string) (client.Client, int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dim, exists, err := s.describe(ctx, collection)
+	return s.cli, dim, exists, err
+}
+
+// describe 连接Milvus并查询集合的向量维度，已有集合会被加载，调用方需持有锁
+func (s *MilvusStore) describe(ctx context.Context, collection string) (int, bool, error) {
+	if s.cli == nil {
+		cli, err := milvus.Connect(ctx, s.cfg)
+		if err != nil {
+			return 0, false, err
+		}
+		s.cli = cli
+	}
+	if dim, ok := s.dims[collection]; ok {
+		return dim, true, nil
+	}
+
+	exists, err := s.cli.HasCollection(ctx, collection)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "查询集合%s失败", collection)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+	coll, err := s.cli.DescribeCollection(ctx, collection)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "查询集合%s结构失败", collection)
+	}
+	dim := -1
+	for _, f := range coll.Schema.Fields {
+		if f.Name == milvusFieldVector {
+			dim, _ = strconv.Atoi(f.TypeParams[entity.TypeParamDim])
+		}
+	}
+	if dim < 0 {
+		return 0, false, errors.Wrapf(ErrDimensionMismatch, "集合%s没有向量字段%s", collection, milvusFieldVector)
+	}
+	if err := s.cli.LoadCollection(ctx, collection, false); err != nil {
+		return 0, false, errors.Wrapf(err, "加载集合%s失败", collection)
+	}
+	s.dims[collection] = dim
+	return dim, true, nil
+}
+
+// milvusExpr 将过滤条件转换为Milvus布尔表达式
+func milvusExpr(filter Filter) (string, error) {
+	keys, err := validateFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	conditions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch v := filter[key].(type) {
+		case string:
+			value = strconv.Quote(v)
+		default:
+			value = fmt.Sprint(v)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s[%q] == %s", milvusFieldMetadata, key, value))
+	}
+	return strings.Join(conditions, " && "), nil
+}
+
+// truncateBytes 按字节截断字符串，不截断多字节字符
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,205 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"myapi/pkg/db"
+	"myapi/pkg/models"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlBatchSize 批量写入记录的条数
+const mysqlBatchSize = 100
+
+// MySQLStore 基于MySQL/TiDB或SQLite的向量存储，复用服务的数据库连接，检索时在内存中逐条计算相似度
+type MySQLStore struct{}
+
+// NewMySQLStore 创建MySQL向量存储，所需的表由数据库迁移000004_vector_store创建
+func NewMySQLStore() *MySQLStore {
+	return &MySQLStore{}
+}
+
+func (s *MySQLStore) CreateCollection(ctx context.Context, collection string, dim int) error {
+	database := db.GetDBWithContext(ctx)
+	coll := models.VectorCollection{Name: collection, Dimensions: dim}
+	if err := database.Clauses(clause.OnConflict{DoNothing: true}).Create(&coll).Error; err != nil {
+		return errors.Wrapf(err, "创建集合%s失败", collection)
+	}
+	current, exists, err := s.dimension(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if exists && current != dim {
+		return errors.Wrapf(ErrDimensionMismatch, "集合%s的向量维度为%d，请求的维度为%d", collection, current, dim)
+	}
+	return nil
+}
+
+func (s *MySQLStore) DropCollection(ctx context.Context, collection string) error {
+	err := db.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection = ?", collection).Delete(&models.VectorRecord{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("name = ?", collection).Delete(&models.VectorCollection{}).Error
+	})
+	if err != nil {
+		return errors.Wrapf(err, "删除集合%s失败", collection)
+	}
+	return nil
+}
+
+func (s *MySQLStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	dim, exists, err := s.dimension(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Wrapf(ErrCollectionNotFound, "集合: %s", collection)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if err := checkVectors(collection, records, dim); err != nil {
+		return err
+	}
+
+	rows := make([]models.VectorRecord, len(records))
+	for i, r := range records {
+		meta := r.Metadata
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		rows[i] = models.VectorRecord{
+			Collection: collection,
+			RecordID:   r.ID,
+			Content:    r.Content,
+			Metadata:   meta,
+			Vector:     encodeVector(normalize(r.Vector)),
+		}
+	}
+	err = db.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"content", "metadata", "vector", "updated_at"}),
+	}).CreateInBatches(&rows, mysqlBatchSize).Error
+	if err != nil {
+		return errors.Wrapf(err, "写入集合%s失败", collection)
+	}
+	return nil
+}
+
+func (s *MySQLStore) Delete(ctx context.Context, collection string, filter Filter) error {
+	if len(filter) == 0 {
+		return errors.Wrap(ErrInvalidFilter, "删除记录时必须指定过滤条件")
+	}
+	query, err := s.query(ctx, collection, filter)
+	if err != nil {
+		return err
+	}
+	if err := query.Delete(&models.VectorRecord{}).Error; err != nil {
+		return errors.Wrapf(err, "删除集合%s的记录失败", collection)
+	}
+	return nil
+}
+
+func (s *MySQLStore) Search(ctx context.Context, collection string, vector []float32, topK int, filter Filter) ([]Result, error) {
+	dim, exists, err := s.dimension(ctx, collection)
+	if err != nil || !exists {
+		return nil, err
+	}
+	if len(vector) != dim {
+		return nil, errors.Wrapf(ErrDimensionMismatch, "查询向量维度为%d，集合%s的维度为%d", len(vector), collection, dim)
+	}
+	query, err := s.query(ctx, collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	target := normalize(vector)
+	var results []Result
+	var batch []models.VectorRecord
+	err = query.FindInBatches(&batch, mysqlBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, r := range batch {
+			results = append(results, Result{
+				ID:       r.RecordID,
+				Content:  r.Content,
+				Metadata: r.Metadata,
+				Score:    dot(target, decodeVector(r.Vector)),
+			})
+		}
+		// 只保留当前的前topK条，避免集合较大时占用过多内存
+		results = topResults(results, topK)
+		return nil
+	}).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "检索集合%s失败", collection)
+	}
+	return results, nil
+}
+
+func (s *MySQLStore) Count(ctx context.Context, collection string, filter Filter) (int64, error) {
+	query, err := s.query(ctx, collection, filter)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, errors.Wrapf(err, "统计集合%s的记录数失败", collection)
+	}
+	return count, nil
+}
+
+func (s *MySQLStore) Close() error {
+	return nil
+}
+
+// dimension 查询集合的向量维度
+func (s *MySQLStore) dimension(ctx context.Context, collection string) (int, bool, error) {
+	var coll models.VectorCollection
+	if err := db.GetDBWithContext(ctx).Where("name = ?", collection).First(&coll).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, errors.Wrapf(err, "查询集合%s失败", collection)
+	}
+	return coll.Dimensions, true, nil
+}
+
+// query 按集合和元数据过滤条件构造查询，元数据统一按字符串比较
+func (s *MySQLStore) query(ctx context.Context, collection string, filter Filter) (*gorm.DB, error) {
+	keys, err := validateFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	query := db.GetDBWithContext(ctx).Model(&models.VectorRecord{}).Where("collection = ?", collection)
+	for _, key := range keys {
+		path, value := "$."+key, fmt.Sprint(filter[key])
+		if db.Dialect() == "sqlite" {
+			// sqlite的json_extract把布尔值返回为1/0，按json_type还原为true/false后再比较
+			query = query.Where("(CASE json_type(metadata, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(json_extract(metadata, ?) AS TEXT) END) = ?", path, path, value)
+			continue
+		}
+		query = query.Where("JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ?", path, value)
+	}
+	return query, nil
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,148 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"myapi/config"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("向量集合不存在")
+	ErrDimensionMismatch  = errors.New("向量维度不一致")
+	ErrInvalidFilter      = errors.New("元数据过滤条件不合法")
+)
+
+// metadataKeyPattern 元数据字段名规则，过滤条件会拼接到查询表达式中
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Record 集合中的一条向量记录，Metadata的值为字符串、数字或布尔值
+type Record struct {
+	ID       string
+	Content  string
+	Metadata map[string]any
+	Vector   []float32
+}
+
+// Result 相似度检索命中的记录，Score为余弦相似度
+type Result struct {
+	ID       string
+	Content  string
+	Metadata map[string]any
+	Score    float32
+}
+
+// Filter 元数据过滤条件，各字段取值相等时匹配，多个字段之间为与关系
+type Filter map[string]any
+
+// Store 向量存储，Search、Count和Delete作用于不存在的集合时返回空结果
+type Store interface {
+	// CreateCollection 创建集合，集合已存在时校验向量维度
+	CreateCollection(ctx context.Context, collection string, dim int) error
+	// DropCollection 删除集合及其全部记录
+	DropCollection(ctx context.Context, collection string) error
+	// Upsert 按ID写入或覆盖记录，集合需要先创建
+	Upsert(ctx context.Context, collection string, records []Record) error
+	// Delete 删除匹配过滤条件的记录，过滤条件不能为空
+	Delete(ctx context.Context, collection string, filter Filter) error
+	// Search 返回匹配过滤条件且与向量最相似的topK条记录，按相似度降序排列
+	Search(ctx context.Context, collection string, vector []float32, topK int, filter Filter) ([]Result, error)
+	// Count 统计匹配过滤条件的记录数
+	Count(ctx context.Context, collection string, filter Filter) (int64, error)
+	// Close 释放存储占用的连接
+	Close() error
+}
+
+// New 按配置创建向量存储
+func New(cfg *config.VectorStoreConfig, milvus *config.MilvusConfig) (Store, error) {
+	switch backend := cfg.ResolveBackend(milvus); backend {
+	case config.VectorStoreMilvus:
+		return NewMilvusStore(milvus), nil
+	case config.VectorStoreMemory:
+		return NewMemoryStore(), nil
+	case config.VectorStoreMySQL:
+		return NewMySQLStore(), nil
+	default:
+		return nil, errors.Errorf("不支持的向量存储后端: %s", backend)
+	}
+}
+
+// validateFilter 校验过滤条件的字段名和取值类型，返回排序后的字段名
+func validateFilter(filter Filter) ([]string, error) {
+	keys := make([]string, 0, len(filter))
+	for key, value := range filter {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, errors.Wrapf(ErrInvalidFilter, "字段名%q不合法", key)
+		}
+		switch value.(type) {
+		case string, bool, int, int32, int64, float32, float64:
+		default:
+			return nil, errors.Wrapf(ErrInvalidFilter, "字段%s的取值类型%T不支持", key, value)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// matchFilter 判断元数据是否匹配过滤条件，数字按字面值比较
+func matchFilter(metadata map[string]any, filter Filter) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkVectors 校验记录的向量维度
+func checkVectors(collection string, records []Record, dim int) error {
+	for _, r := range records {
+		if len(r.Vector) != dim {
+			return errors.Wrapf(ErrDimensionMismatch, "记录[%s]的向量维度为%d，集合%s的维度为%d", r.ID, len(r.Vector), collection, dim)
+		}
+	}
+	return nil
+}
+
+// normalize 返回单位化后的向量副本，余弦相似度即为单位向量的点积
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// topResults 按相似度降序排列并截取前topK条
+func topResults(results []Result, topK int) []Result {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
@@ -0,0 +1,226 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"myapi/pkg/db/dbtest"
+
+	"github.com/google/uuid"
+)
+
+// testStores 需要相同行为的向量存储实现，Milvus需要外部服务，不在此测试
+var testStores = []struct {
+	name     string
+	newStore func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store { return NewMemoryStore() }},
+	{"mysql", func(t *testing.T) Store {
+		dbtest.Setup(t)
+		return NewMySQLStore()
+	}},
+}
+
+// forEachStore 对每种实现运行测试，集合名随机生成，测试结束后删除
+func forEachStore(t *testing.T, dim int, test func(t *testing.T, store Store, collection string)) {
+	for _, ts := range testStores {
+		t.Run(ts.name, func(t *testing.T) {
+			store := ts.newStore(t)
+			collection := "test_" + uuid.New().String()
+			if err := store.CreateCollection(context.Background(), collection, dim); err != nil {
+				t.Fatalf("创建集合失败: %v", err)
+			}
+			t.Cleanup(func() {
+				_ = store.DropCollection(context.Background(), collection)
+				_ = store.Close()
+			})
+			test(t, store, collection)
+		})
+	}
+}
+
+func TestStoreFilterValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		valid  bool
+	}{
+		{"字符串", Filter{"doc_id": "d1"}, true},
+		{"数字和布尔值", Filter{"page": 3, "score": 0.5, "public": true}, true},
+		{"字段名以数字开头", Filter{"1doc": "d1"}, false},
+		{"字段名包含引号", Filter{"doc' OR 1=1": "d1"}, false},
+		{"字段名包含点号", Filter{"a.b": "d1"}, false},
+		{"字段名过长", Filter{strings.Repeat("a", 65): "d1"}, false},
+		{"取值为切片", Filter{"tags": []string{"a"}}, false},
+		{"取值为nil", Filter{"doc_id": nil}, false},
+	}
+	forEachStore(t, 2, func(t *testing.T, store Store, collection string) {
+		ctx := context.Background()
+		for _, tt := range tests {
+			_, searchErr := store.Search(ctx, collection, []float32{1, 0}, 10, tt.filter)
+			_, countErr := store.Count(ctx, collection, tt.filter)
+			deleteErr := store.Delete(ctx, collection, tt.filter)
+			for op, err := range map[string]error{"Search": searchErr, "Count": countErr, "Delete": deleteErr} {
+				if tt.valid && err != nil {
+					t.Errorf("%s: %s返回%v，期望成功", tt.name, op, err)
+				}
+				if !tt.valid && !errors.Is(err, ErrInvalidFilter) {
+					t.Errorf("%s: %s返回%v，期望ErrInvalidFilter", tt.name, op, err)
+				}
+			}
+		}
+		if err := store.Delete(ctx, collection, nil); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("没有过滤条件时Delete返回%v，期望ErrInvalidFilter", err)
+		}
+	})
+}
+
+func TestStoreDimensionMismatch(t *testing.T) {
+	forEachStore(t, 3, func(t *testing.T, store Store, collection string) {
+		ctx := context.Background()
+		if err := store.CreateCollection(ctx, collection, 3); err != nil {
+			t.Errorf("以相同维度重复创建集合返回%v，期望成功", err)
+		}
+		if err := store.CreateCollection(ctx, collection, 4); !errors.Is(err, ErrDimensionMismatch) {
+			t.Errorf("以不同维度创建已有集合返回%v，期望ErrDimensionMismatch", err)
+		}
+		err := store.Upsert(ctx, collection, []Record{
+			{ID: "ok", Vector: []float32{1, 0, 0}},
+			{ID: "bad", Vector: []float32{1, 0}},
+		})
+		if !errors.Is(err, ErrDimensionMismatch) {
+			t.Errorf("写入维度不一致的向量返回%v，期望ErrDimensionMismatch", err)
+		}
+		if count, err := store.Count(ctx, collection, nil); err != nil || count != 0 {
+			t.Errorf("写入失败后记录数为%d(%v)，期望整批不写入", count, err)
+		}
+		if _, err := store.Search(ctx, collection, []float32{1, 0}, 1, nil); !errors.Is(err, ErrDimensionMismatch) {
+			t.Errorf("以不同维度的向量检索返回%v，期望ErrDimensionMismatch", err)
+		}
+	})
+}
+
+func TestStoreUpsertIdempotent(t *testing.T) {
+	records := []Record{
+		{ID: "a", Content: "甲", Metadata: map[string]any{"doc_id": "d1", "page": 1, "public": true}, Vector: []float32{1, 0}},
+		{ID: "b", Content: "乙", Metadata: map[string]any{"doc_id": "d1", "page": 2, "public": false}, Vector: []float32{0, 1}},
+		{ID: "c", Content: "丙", Metadata: map[string]any{"doc_id": "d2", "page": 1, "public": true}, Vector: []float32{1, 1}},
+	}
+	forEachStore(t, 2, func(t *testing.T, store Store, collection string) {
+		ctx := context.Background()
+		for i := 0; i < 2; i++ {
+			if err := store.Upsert(ctx, collection, records); err != nil {
+				t.Fatalf("第%d次写入失败: %v", i+1, err)
+			}
+		}
+		counts := []struct {
+			filter Filter
+			want   int64
+		}{
+			{nil, 3},
+			{Filter{"doc_id": "d1"}, 2},
+			{Filter{"page": 1}, 2},
+			{Filter{"public": true}, 2},
+			{Filter{"doc_id": "d1", "public": false}, 1},
+			{Filter{"doc_id": "d3"}, 0},
+		}
+		for _, c := range counts {
+			if count, err := store.Count(ctx, collection, c.filter); err != nil || count != c.want {
+				t.Errorf("过滤条件%v的记录数为%d(%v)，期望%d", c.filter, count, err, c.want)
+			}
+		}
+
+		// 相同ID覆盖原有的内容、元数据和向量
+		if err := store.Upsert(ctx, collection, []Record{
+			{ID: "a", Content: "甲2", Metadata: map[string]any{"doc_id": "d2"}, Vector: []float32{0, 1}},
+		}); err != nil {
+			t.Fatalf("覆盖记录失败: %v", err)
+		}
+		results, err := store.Search(ctx, collection, []float32{0, 1}, 10, Filter{"doc_id": "d2"})
+		if err != nil {
+			t.Fatalf("检索失败: %v", err)
+		}
+		if len(results) != 2 || results[0].ID != "a" || results[0].Content != "甲2" {
+			t.Errorf("覆盖后的检索结果为%+v，期望记录a排在第一位且内容已更新", results)
+		}
+
+		if err := store.Delete(ctx, collection, Filter{"doc_id": "d2"}); err != nil {
+			t.Fatalf("删除记录失败: %v", err)
+		}
+		if count, err := store.Count(ctx, collection, nil); err != nil || count != 1 {
+			t.Errorf("删除后记录数为%d(%v)，期望1", count, err)
+		}
+	})
+}
+
+func TestStoreSearchTopK(t *testing.T) {
+	// 与查询向量(1,0)的夹角依次增大，余弦相似度依次减小
+	angles := map[string]float64{"r0": 0, "r1": 20, "r2": 45, "r3": 90, "r4": 180}
+	records := make([]Record, 0, len(angles))
+	for id, deg := range angles {
+		rad := deg * math.Pi / 180
+		// 向量长度不同，相似度只取决于方向
+		scale := float32(len(records) + 1)
+		records = append(records, Record{
+			ID:       id,
+			Metadata: map[string]any{"even": id == "r0" || id == "r2" || id == "r4"},
+			Vector:   []float32{scale * float32(math.Cos(rad)), scale * float32(math.Sin(rad))},
+		})
+	}
+	tests := []struct {
+		name   string
+		topK   int
+		filter Filter
+		want   []string
+	}{
+		{"前3条", 3, nil, []string{"r0", "r1", "r2"}},
+		{"topK大于记录数", 10, nil, []string{"r0", "r1", "r2", "r3", "r4"}},
+		{"先过滤再取topK", 2, Filter{"even": false}, []string{"r1", "r3"}},
+	}
+	forEachStore(t, 2, func(t *testing.T, store Store, collection string) {
+		ctx := context.Background()
+		if err := store.Upsert(ctx, collection, records); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		for _, tt := range tests {
+			results, err := store.Search(ctx, collection, []float32{2, 0}, tt.topK, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: 检索失败: %v", tt.name, err)
+			}
+			ids := make([]string, len(results))
+			for i, r := range results {
+				ids[i] = r.ID
+				want := float32(math.Cos(angles[r.ID] * math.Pi / 180))
+				if math.Abs(float64(r.Score-want)) > 1e-5 {
+					t.Errorf("%s: 记录%s的相似度为%f，期望%f", tt.name, r.ID, r.Score, want)
+				}
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("%s: 检索结果为%v，期望%v", tt.name, ids, tt.want)
+			}
+		}
+	})
+}
+
+func TestStoreMissingCollection(t *testing.T) {
+	for _, ts := range testStores {
+		t.Run(ts.name, func(t *testing.T) {
+			store := ts.newStore(t)
+			ctx := context.Background()
+			collection := "missing_" + uuid.New().String()
+			if err := store.Upsert(ctx, collection, []Record{{ID: "a", Vector: []float32{1}}}); !errors.Is(err, ErrCollectionNotFound) {
+				t.Errorf("写入不存在的集合返回%v，期望ErrCollectionNotFound", err)
+			}
+			if results, err := store.Search(ctx, collection, []float32{1}, 1, nil); err != nil || len(results) != 0 {
+				t.Errorf("检索不存在的集合返回%v(%v)，期望空结果", results, err)
+			}
+			if count, err := store.Count(ctx, collection, nil); err != nil || count != 0 {
+				t.Errorf("统计不存在的集合返回%d(%v)，期望0", count, err)
+			}
+		})
+	}
+}